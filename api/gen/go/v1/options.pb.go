@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.35.2
+// 	protoc        (unknown)
+// source: v1/options.proto
+
+package v1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Access level a procedure requires
+type AuthLevel int32
+
+const (
+	AuthLevel_AUTH_LEVEL_UNSPECIFIED AuthLevel = 0
+	// Callable without credentials
+	AuthLevel_AUTH_LEVEL_PUBLIC AuthLevel = 1
+	// Requires a JWT, session cookie or API key
+	AuthLevel_AUTH_LEVEL_AUTHENTICATED AuthLevel = 2
+)
+
+// Enum value maps for AuthLevel.
+var (
+	AuthLevel_name = map[int32]string{
+		0: "AUTH_LEVEL_UNSPECIFIED",
+		1: "AUTH_LEVEL_PUBLIC",
+		2: "AUTH_LEVEL_AUTHENTICATED",
+	}
+	AuthLevel_value = map[string]int32{
+		"AUTH_LEVEL_UNSPECIFIED":   0,
+		"AUTH_LEVEL_PUBLIC":        1,
+		"AUTH_LEVEL_AUTHENTICATED": 2,
+	}
+)
+
+func (x AuthLevel) Enum() *AuthLevel {
+	p := new(AuthLevel)
+	*p = x
+	return p
+}
+
+func (x AuthLevel) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AuthLevel) Descriptor() protoreflect.EnumDescriptor {
+	return file_v1_options_proto_enumTypes[0].Descriptor()
+}
+
+func (AuthLevel) Type() protoreflect.EnumType {
+	return &file_v1_options_proto_enumTypes[0]
+}
+
+func (x AuthLevel) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AuthLevel.Descriptor instead.
+func (AuthLevel) EnumDescriptor() ([]byte, []int) {
+	return file_v1_options_proto_rawDescGZIP(), []int{0}
+}
+
+// Role a caller may hold beyond being authenticated
+type Role int32
+
+const (
+	Role_ROLE_UNSPECIFIED Role = 0
+	// Users listed in auth.admin_emails
+	Role_ROLE_ADMIN Role = 1
+)
+
+// Enum value maps for Role.
+var (
+	Role_name = map[int32]string{
+		0: "ROLE_UNSPECIFIED",
+		1: "ROLE_ADMIN",
+	}
+	Role_value = map[string]int32{
+		"ROLE_UNSPECIFIED": 0,
+		"ROLE_ADMIN":       1,
+	}
+)
+
+func (x Role) Enum() *Role {
+	p := new(Role)
+	*p = x
+	return p
+}
+
+func (x Role) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Role) Descriptor() protoreflect.EnumDescriptor {
+	return file_v1_options_proto_enumTypes[1].Descriptor()
+}
+
+func (Role) Type() protoreflect.EnumType {
+	return &file_v1_options_proto_enumTypes[1]
+}
+
+func (x Role) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Role.Descriptor instead.
+func (Role) EnumDescriptor() ([]byte, []int) {
+	return file_v1_options_proto_rawDescGZIP(), []int{1}
+}
+
+// Access policy declared on every RPC with the (api.v1.auth) method option
+type AuthPolicy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Level AuthLevel `protobuf:"varint,1,opt,name=level,proto3,enum=api.v1.AuthLevel" json:"level,omitempty"`
+	// Scopes the credential must carry; unscoped credentials are granted every scope
+	Scopes []string `protobuf:"bytes,2,rep,name=scopes,proto3" json:"scopes,omitempty"`
+	// Roles of which the caller must hold at least one; only valid with AUTH_LEVEL_AUTHENTICATED
+	Roles []Role `protobuf:"varint,3,rep,packed,name=roles,proto3,enum=api.v1.Role" json:"roles,omitempty"`
+}
+
+func (x *AuthPolicy) Reset() {
+	*x = AuthPolicy{}
+	mi := &file_v1_options_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthPolicy) ProtoMessage() {}
+
+func (x *AuthPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_options_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthPolicy.ProtoReflect.Descriptor instead.
+func (*AuthPolicy) Descriptor() ([]byte, []int) {
+	return file_v1_options_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AuthPolicy) GetLevel() AuthLevel {
+	if x != nil {
+		return x.Level
+	}
+	return AuthLevel_AUTH_LEVEL_UNSPECIFIED
+}
+
+func (x *AuthPolicy) GetScopes() []string {
+	if x != nil {
+		return x.Scopes
+	}
+	return nil
+}
+
+func (x *AuthPolicy) GetRoles() []Role {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+var file_v1_options_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*AuthPolicy)(nil),
+		Field:         50001,
+		Name:          "api.v1.auth",
+		Tag:           "bytes,50001,opt,name=auth",
+		Filename:      "v1/options.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// optional api.v1.AuthPolicy auth = 50001;
+	E_Auth = &file_v1_options_proto_extTypes[0]
+)
+
+var File_v1_options_proto protoreflect.FileDescriptor
+
+var file_v1_options_proto_rawDesc = []byte{
+	0x0a, 0x10, 0x76, 0x31, 0x2f, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x06, 0x61, 0x70, 0x69, 0x2e, 0x76, 0x31, 0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73, 0x63,
+	0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x71, 0x0a, 0x0a,
+	0x41, 0x75, 0x74, 0x68, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x27, 0x0a, 0x05, 0x6c, 0x65,
+	0x76, 0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x61, 0x70, 0x69, 0x2e,
+	0x76, 0x31, 0x2e, 0x41, 0x75, 0x74, 0x68, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x52, 0x05, 0x6c, 0x65,
+	0x76, 0x65, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x73, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x06, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x73, 0x12, 0x22, 0x0a, 0x05, 0x72,
+	0x6f, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x0c, 0x2e, 0x61, 0x70, 0x69,
+	0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x6c, 0x65, 0x52, 0x05, 0x72, 0x6f, 0x6c, 0x65, 0x73, 0x2a,
+	0x5c, 0x0a, 0x09, 0x41, 0x75, 0x74, 0x68, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x1a, 0x0a, 0x16,
+	0x41, 0x55, 0x54, 0x48, 0x5f, 0x4c, 0x45, 0x56, 0x45, 0x4c, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45,
+	0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x15, 0x0a, 0x11, 0x41, 0x55, 0x54, 0x48,
+	0x5f, 0x4c, 0x45, 0x56, 0x45, 0x4c, 0x5f, 0x50, 0x55, 0x42, 0x4c, 0x49, 0x43, 0x10, 0x01, 0x12,
+	0x1c, 0x0a, 0x18, 0x41, 0x55, 0x54, 0x48, 0x5f, 0x4c, 0x45, 0x56, 0x45, 0x4c, 0x5f, 0x41, 0x55,
+	0x54, 0x48, 0x45, 0x4e, 0x54, 0x49, 0x43, 0x41, 0x54, 0x45, 0x44, 0x10, 0x02, 0x2a, 0x2c, 0x0a,
+	0x04, 0x52, 0x6f, 0x6c, 0x65, 0x12, 0x14, 0x0a, 0x10, 0x52, 0x4f, 0x4c, 0x45, 0x5f, 0x55, 0x4e,
+	0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x52,
+	0x4f, 0x4c, 0x45, 0x5f, 0x41, 0x44, 0x4d, 0x49, 0x4e, 0x10, 0x01, 0x3a, 0x48, 0x0a, 0x04, 0x61,
+	0x75, 0x74, 0x68, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x18, 0xd1, 0x86, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x61, 0x70,
+	0x69, 0x2e, 0x76, 0x31, 0x2e, 0x41, 0x75, 0x74, 0x68, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52,
+	0x04, 0x61, 0x75, 0x74, 0x68, 0x42, 0x2a, 0x5a, 0x28, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x64, 0x61, 0x6d, 0x65, 0x6a, 0x65, 0x72, 0x61, 0x73, 0x2f, 0x67, 0x6f,
+	0x6f, 0x73, 0x65, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x67, 0x6f, 0x2f, 0x76,
+	0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_v1_options_proto_rawDescOnce sync.Once
+	file_v1_options_proto_rawDescData = file_v1_options_proto_rawDesc
+)
+
+func file_v1_options_proto_rawDescGZIP() []byte {
+	file_v1_options_proto_rawDescOnce.Do(func() {
+		file_v1_options_proto_rawDescData = protoimpl.X.CompressGZIP(file_v1_options_proto_rawDescData)
+	})
+	return file_v1_options_proto_rawDescData
+}
+
+var file_v1_options_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_v1_options_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_v1_options_proto_goTypes = []any{
+	(AuthLevel)(0),                     // 0: api.v1.AuthLevel
+	(Role)(0),                          // 1: api.v1.Role
+	(*AuthPolicy)(nil),                 // 2: api.v1.AuthPolicy
+	(*descriptorpb.MethodOptions)(nil), // 3: google.protobuf.MethodOptions
+}
+var file_v1_options_proto_depIdxs = []int32{
+	0, // 0: api.v1.AuthPolicy.level:type_name -> api.v1.AuthLevel
+	1, // 1: api.v1.AuthPolicy.roles:type_name -> api.v1.Role
+	3, // 2: api.v1.auth:extendee -> google.protobuf.MethodOptions
+	2, // 3: api.v1.auth:type_name -> api.v1.AuthPolicy
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	3, // [3:4] is the sub-list for extension type_name
+	2, // [2:3] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_v1_options_proto_init() }
+func file_v1_options_proto_init() {
+	if File_v1_options_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_v1_options_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_v1_options_proto_goTypes,
+		DependencyIndexes: file_v1_options_proto_depIdxs,
+		EnumInfos:         file_v1_options_proto_enumTypes,
+		MessageInfos:      file_v1_options_proto_msgTypes,
+		ExtensionInfos:    file_v1_options_proto_extTypes,
+	}.Build()
+	File_v1_options_proto = out.File
+	file_v1_options_proto_rawDesc = nil
+	file_v1_options_proto_goTypes = nil
+	file_v1_options_proto_depIdxs = nil
+}
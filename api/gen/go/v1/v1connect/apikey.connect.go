@@ -80,6 +80,7 @@ func NewAPIKeyServiceClient(httpClient connect.HTTPClient, baseURL string, opts
 			httpClient,
 			baseURL+APIKeyServiceListAPIKeysProcedure,
 			connect.WithSchema(aPIKeyServiceMethods.ByName("ListAPIKeys")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
 			connect.WithClientOptions(opts...),
 		),
 		deleteAPIKey: connect.NewClient[v1.DeleteAPIKeyRequest, v1.DeleteAPIKeyResponse](
@@ -154,6 +155,7 @@ func NewAPIKeyServiceHandler(svc APIKeyServiceHandler, opts ...connect.HandlerOp
 		APIKeyServiceListAPIKeysProcedure,
 		svc.ListAPIKeys,
 		connect.WithSchema(aPIKeyServiceMethods.ByName("ListAPIKeys")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
 		connect.WithHandlerOptions(opts...),
 	)
 	aPIKeyServiceDeleteAPIKeyHandler := connect.NewUnaryHandler(
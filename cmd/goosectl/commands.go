@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/pkg/client"
+)
+
+type loginOptions struct {
+	apiKey string
+	token  string
+}
+
+func loginFlags(fs *flag.FlagSet, a *app) {
+	fs.StringVar(&a.login.apiKey, "api-key", "", `API key; "-" reads it from stdin`)
+	fs.StringVar(&a.login.token, "token", "", `JWT access token; "-" reads it from stdin`)
+}
+
+type apiKeyOptions struct {
+	scopes []string
+}
+
+func apiKeyFlags(fs *flag.FlagSet, a *app) {
+	fs.Func("scope", "Limit a created key to this scope, e.g. api_keys:read; repeatable", func(scope string) error {
+		a.apiKeys.scopes = append(a.apiKeys.scopes, scope)
+		return nil
+	})
+}
+
+// formatScopes lists the scopes of a key; keys without scopes have full access
+func formatScopes(scopes []string) string {
+	if len(scopes) == 0 {
+		return "all"
+	}
+	return strings.Join(scopes, ",")
+}
+
+func runLogin(ctx context.Context, a *app, args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	apiKey, token := &a.login.apiKey, &a.login.token
+	if *apiKey != "" && *token != "" {
+		fmt.Fprintln(a.stderr, "login takes at most one of --api-key and --token")
+		return errUsage
+	}
+	for _, value := range []*string{apiKey, token} {
+		if *value == "-" {
+			line, err := bufio.NewReader(a.stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("read credentials from stdin: %w", err)
+			}
+			*value = strings.TrimSpace(line)
+		}
+	}
+
+	p := a.profile()
+	previous := *p
+	if a.server != "" {
+		p.Server = a.server
+	}
+	p.APIKey, p.Token = *apiKey, *token
+
+	c, err := a.client()
+	if err != nil {
+		*p = previous
+		return err
+	}
+	var user *v1.User
+	if *apiKey == "" && *token == "" {
+		user, err = deviceLogin(ctx, a, c)
+	} else {
+		// check the credentials before saving them
+		var res *connect.Response[v1.GetCurrentUserResponse]
+		if res, err = c.Auth.GetCurrentUser(ctx, connect.NewRequest(&v1.GetCurrentUserRequest{})); err == nil {
+			user = res.Msg.GetUser()
+		}
+	}
+	if err != nil {
+		*p = previous
+		return err
+	}
+
+	if a.profiles.Current == "" {
+		a.profiles.Current = a.profileName
+	}
+	if err := a.profiles.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "Logged in to %s as %s (profile %q)\n", p.Server, user.GetEmail(), a.profileName)
+	return nil
+}
+
+// deviceLogin asks the user to approve the login in a browser and stores the
+// token it receives in the profile
+func deviceLogin(ctx context.Context, a *app, c *client.Client) (*v1.User, error) {
+	clientName := "goosectl"
+	if host, err := os.Hostname(); err == nil {
+		clientName += " on " + host
+	}
+	// polling outlives the request timeout; the code expiry bounds it
+	res, err := c.DeviceLogin(ctx, clientName, func(codes *v1.StartDeviceAuthResponse) {
+		fmt.Fprintf(a.stderr, "To log in, open %s\nand check that it shows the code %s\n\nWaiting for approval...\n", codes.GetVerificationUriComplete(), codes.GetUserCode())
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.profile().Token = res.GetJwt()
+	return res.GetUser(), nil
+}
+
+func runLogout(ctx context.Context, a *app, args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	p, ok := a.profiles.Profiles[a.profileName]
+	if !ok || (p.APIKey == "" && p.Token == "") {
+		fmt.Fprintf(a.stderr, "Profile %q has no credentials\n", a.profileName)
+		return nil
+	}
+	// API keys stay valid until deleted; sessions end on the server as well
+	if p.APIKey == "" {
+		if c, err := a.client(); err == nil {
+			if _, err := c.Auth.Logout(ctx, connect.NewRequest(&v1.LogoutRequest{})); err != nil {
+				fmt.Fprintf(a.stderr, "Server logout failed: %s\n", errorMessage(err))
+			}
+		}
+	}
+	p.APIKey, p.Token = "", ""
+	if err := a.profiles.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "Logged out of profile %q\n", a.profileName)
+	return nil
+}
+
+func runWhoami(ctx context.Context, a *app, args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	res, err := c.Auth.GetCurrentUser(ctx, connect.NewRequest(&v1.GetCurrentUserRequest{}))
+	if err != nil {
+		return err
+	}
+	user := res.Msg.GetUser()
+	return a.out.print(user, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "ID\t%d\n", user.GetId())
+		fmt.Fprintf(w, "Email\t%s\n", user.GetEmail())
+		fmt.Fprintf(w, "Name\t%s\n", user.GetName())
+	})
+}
+
+func runAPIKeys(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	switch sub, args := args[0], args[1:]; {
+	case sub == "list" && len(args) == 0:
+		res, err := c.APIKeys.ListAPIKeys(ctx, connect.NewRequest(&v1.ListAPIKeysRequest{}))
+		if err != nil {
+			return err
+		}
+		return a.out.print(res.Msg, func(w *tabwriter.Writer) {
+			fmt.Fprintln(w, "ID\tNAME\tKEY\tSCOPES\tCREATED\tLAST USED")
+			for _, key := range res.Msg.GetApiKeys() {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", key.GetId(), key.GetName(), key.GetKeyMasked(), formatScopes(key.GetScopes()), formatTime(key.GetCreatedAt()), formatTime(key.GetLastUsedAt()))
+			}
+		})
+
+	case sub == "create" && len(args) == 1:
+		res, err := c.APIKeys.CreateAPIKey(ctx, connect.NewRequest(&v1.CreateAPIKeyRequest{Name: args[0], Scopes: a.apiKeys.scopes}))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(a.stderr, "Store the key now, it can't be shown again")
+		return a.out.print(res.Msg, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "ID\t%s\n", res.Msg.GetId())
+			fmt.Fprintf(w, "Name\t%s\n", res.Msg.GetName())
+			fmt.Fprintf(w, "Key\t%s\n", res.Msg.GetKey())
+			fmt.Fprintf(w, "Scopes\t%s\n", formatScopes(res.Msg.GetScopes()))
+		})
+
+	case sub == "rename" && len(args) == 2:
+		res, err := c.APIKeys.UpdateAPIKey(ctx, connect.NewRequest(&v1.UpdateAPIKeyRequest{Id: args[0], Name: args[1]}))
+		if err != nil {
+			return err
+		}
+		key := res.Msg.GetApiKey()
+		return a.out.print(key, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "ID\t%s\n", key.GetId())
+			fmt.Fprintf(w, "Name\t%s\n", key.GetName())
+			fmt.Fprintf(w, "Key\t%s\n", key.GetKeyMasked())
+		})
+
+	case sub == "delete" && len(args) == 1:
+		res, err := c.APIKeys.DeleteAPIKey(ctx, connect.NewRequest(&v1.DeleteAPIKeyRequest{Id: args[0]}))
+		if err != nil {
+			return err
+		}
+		return a.out.print(res.Msg, func(w *tabwriter.Writer) {
+			fmt.Fprintf(w, "Deleted %s\n", args[0])
+		})
+	}
+
+	fmt.Fprintln(a.stderr, "usage: goosectl api-keys list | create NAME [--scope SCOPE]... | rename ID NAME | delete ID")
+	return errUsage
+}
+
+func runProfiles(_ context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch sub, args := args[0], args[1:]; {
+	case sub == "list" && len(args) == 0:
+		if a.out.format != outputTable {
+			// never print saved credentials
+			list := make([]map[string]any, 0, len(a.profiles.Profiles))
+			for _, name := range a.profiles.names() {
+				list = append(list, profileSummary(a, name))
+			}
+			return a.out.printValue(list)
+		}
+		w := tabwriter.NewWriter(a.out.w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CURRENT\tNAME\tSERVER\tCREDENTIALS")
+		for _, name := range a.profiles.names() {
+			s := profileSummary(a, name)
+			current := ""
+			if s["current"] == true {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", current, name, s["server"], s["credentials"])
+		}
+		return w.Flush()
+
+	case sub == "use" && len(args) == 1:
+		if _, ok := a.profiles.Profiles[args[0]]; !ok {
+			return fmt.Errorf("no profile %q; create it with goosectl --profile %s --server URL login", args[0], args[0])
+		}
+		a.profiles.Current = args[0]
+		return a.profiles.save()
+
+	case sub == "delete" && len(args) == 1:
+		if _, ok := a.profiles.Profiles[args[0]]; !ok {
+			return fmt.Errorf("no profile %q", args[0])
+		}
+		delete(a.profiles.Profiles, args[0])
+		if a.profiles.Current == args[0] {
+			a.profiles.Current = ""
+		}
+		return a.profiles.save()
+	}
+
+	fmt.Fprintln(a.stderr, "usage: goosectl profiles [list | use NAME | delete NAME]")
+	return errUsage
+}
+
+func profileSummary(a *app, name string) map[string]any {
+	p := a.profiles.Profiles[name]
+	credentials := "none"
+	switch {
+	case p.APIKey != "":
+		credentials = "api key"
+	case p.Token != "":
+		credentials = "token"
+	}
+	return map[string]any{
+		"name":        name,
+		"server":      p.Server,
+		"credentials": credentials,
+		"current":     name == a.profileName,
+	}
+}
+
+func runVersion(_ context.Context, a *app, args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	_, err := fmt.Fprintf(a.out.w, "goosectl %s\n", version)
+	return err
+}
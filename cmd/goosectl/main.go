@@ -0,0 +1,242 @@
+// goosectl manages a goose server from the command line
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/damejeras/goose/pkg/client"
+)
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+// errUsage is returned after the usage has been printed
+var errUsage = errors.New("usage")
+
+type command struct {
+	name  string
+	args  string
+	short string
+	// flags registers the flags of the command
+	flags func(fs *flag.FlagSet, app *app)
+	run   func(ctx context.Context, app *app, args []string) error
+}
+
+var commands = []command{
+	{name: "login", args: "[--api-key KEY | --token JWT]", short: "Log in to the server of the profile; without flags, approve the login in a browser", flags: loginFlags, run: runLogin},
+	{name: "logout", short: "Forget the credentials of the profile", run: runLogout},
+	{name: "whoami", short: "Show the current user", run: runWhoami},
+	{name: "api-keys", args: "list | create NAME [--scope SCOPE]... | rename ID NAME | delete ID", short: "Manage your API keys", flags: apiKeyFlags, run: runAPIKeys},
+	{name: "profiles", args: "[list | use NAME | delete NAME]", short: "List, select or delete profiles", run: runProfiles},
+	{name: "version", short: "Print the version", run: runVersion},
+}
+
+// app is the state shared by commands
+type app struct {
+	profiles    *profileFile
+	profileName string
+	// server overrides the server of the profile
+	server  string
+	timeout time.Duration
+	out     *printer
+	login   loginOptions
+	apiKeys apiKeyOptions
+	stdin   io.Reader
+	stderr  io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	err := run(ctx, os.Args[1:])
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goosectl: %s\n", errorMessage(err))
+		os.Exit(1)
+	}
+}
+
+// globalFlags may be given before or after the command
+type globalFlags struct {
+	profile string
+	server  string
+	output  string
+	timeout time.Duration
+}
+
+// register adds the flags to fs, keeping values parsed earlier
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.profile, "profile", g.profile, "Profile to use; defaults to $GOOSECTL_PROFILE or the current profile")
+	fs.StringVar(&g.server, "server", g.server, "Server URL, e.g. https://goose.example.com; overrides the profile")
+	fs.StringVar(&g.output, "output", g.output, "Output format: table, json or yaml")
+	fs.StringVar(&g.output, "o", g.output, "Shorthand for --output")
+	fs.DurationVar(&g.timeout, "timeout", g.timeout, "Timeout of each request")
+}
+
+func run(ctx context.Context, args []string) error {
+	global := globalFlags{server: os.Getenv("GOOSECTL_SERVER"), output: outputTable, timeout: 30 * time.Second}
+	fs := flag.NewFlagSet("goosectl", flag.ContinueOnError)
+	global.register(fs)
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	name := fs.Arg(0)
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		fs.Usage()
+		return errUsage
+	}
+
+	a := &app{
+		out:    &printer{w: os.Stdout},
+		stdin:  os.Stdin,
+		stderr: os.Stderr,
+	}
+	cmdFlags := flag.NewFlagSet("goosectl "+name, flag.ContinueOnError)
+	global.register(cmdFlags)
+	if cmd.flags != nil {
+		cmd.flags(cmdFlags, a)
+	}
+	cmdArgs, err := parseInterspersed(cmdFlags, fs.Args()[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+	switch global.output {
+	case outputTable, outputJSON, outputYAML:
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format %q, use table, json or yaml\n", global.output)
+		return errUsage
+	}
+
+	profiles, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	a.profiles = profiles
+	a.profileName = profiles.selected(global.profile)
+	a.server = global.server
+	a.timeout = global.timeout
+	a.out.format = global.output
+	return cmd.run(ctx, a, cmdArgs)
+}
+
+// parseInterspersed parses flags anywhere among the arguments and returns
+// the rest; "--" ends the flags
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		remaining := fs.Args()
+		if parsed := len(args) - len(remaining); parsed > 0 && args[parsed-1] == "--" {
+			return append(rest, remaining...), nil
+		}
+		if len(remaining) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, remaining[0])
+		args = remaining[1:]
+	}
+}
+
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintf(w, "Usage: goosectl [flags] <command> [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.short)
+		if cmd.args != "" {
+			fmt.Fprintf(w, "  %-10s   goosectl %s %s\n", "", cmd.name, cmd.args)
+		}
+	}
+	fmt.Fprintf(w, "\nFlags:\n")
+	fs.PrintDefaults()
+	fmt.Fprintf(w, "\nProfiles are stored in $GOOSECTL_CONFIG, or goose/goosectl.yaml in the user config directory.\n"+
+		"$GOOSECTL_SERVER and $GOOSECTL_API_KEY connect without a saved profile.\n")
+}
+
+// profile returns the selected profile, creating it if needed
+func (a *app) profile() *profile {
+	p, ok := a.profiles.Profiles[a.profileName]
+	if !ok {
+		p = &profile{}
+		a.profiles.Profiles[a.profileName] = p
+	}
+	return p
+}
+
+// client connects to the server of the profile with its credentials, or
+// with $GOOSECTL_API_KEY
+func (a *app) client() (*client.Client, error) {
+	p := a.profile()
+	server := a.server
+	if server == "" {
+		server = p.Server
+	}
+	if server == "" {
+		return nil, fmt.Errorf("no server for profile %q; run goosectl --server URL login", a.profileName)
+	}
+	var credentials client.Credentials
+	switch {
+	case os.Getenv("GOOSECTL_API_KEY") != "":
+		credentials = client.APIKey(os.Getenv("GOOSECTL_API_KEY"))
+	case p.APIKey != "":
+		credentials = client.APIKey(p.APIKey)
+	case p.Token != "":
+		credentials = client.JWT(p.Token)
+	}
+	return client.New(server, client.Config{
+		Credentials: credentials,
+		Timeout:     a.timeout,
+		UserAgent:   "goosectl/" + version,
+	})
+}
+
+// errorMessage drops the code prefix of errors from the server, which
+// repeats what the message says
+func errorMessage(err error) string {
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		return err.Error()
+	}
+	switch connectErr.Code() {
+	case connect.CodeUnauthenticated:
+		return connectErr.Message() + "; run goosectl login"
+	case connect.CodeUnavailable:
+		return "server unavailable: " + connectErr.Message()
+	}
+	if connectErr.Message() == "" {
+		return err.Error()
+	}
+	return connectErr.Message()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultProfile is used until another profile is selected
+const defaultProfile = "default"
+
+// profileFile holds one profile per server, and which one commands use
+type profileFile struct {
+	Current  string              `yaml:"current"`
+	Profiles map[string]*profile `yaml:"profiles"`
+
+	path string
+}
+
+type profile struct {
+	Server string `yaml:"server"`
+	// APIKey or Token authenticates; APIKey wins when both are set
+	APIKey string `yaml:"api_key,omitempty"`
+	Token  string `yaml:"token,omitempty"`
+}
+
+// profilePath is $GOOSECTL_CONFIG, or goose/goosectl.yaml in the user config directory
+func profilePath() (string, error) {
+	if path := os.Getenv("GOOSECTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goose", "goosectl.yaml"), nil
+}
+
+// loadProfiles reads the profile file; a missing file has no profiles
+func loadProfiles() (*profileFile, error) {
+	path, err := profilePath()
+	if err != nil {
+		return nil, fmt.Errorf("locate profile file: %w", err)
+	}
+	f := &profileFile{path: path, Profiles: map[string]*profile{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read profile file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parse profile file %s: %w", path, err)
+	}
+	if f.Profiles == nil {
+		f.Profiles = map[string]*profile{}
+	}
+	return f, nil
+}
+
+// save writes the file readable by the owner only, since it holds credentials
+func (f *profileFile) save() error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return fmt.Errorf("create profile directory: %w", err)
+	}
+	// write and rename, so that an interrupted write keeps the old file
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write profile file: %w", err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("write profile file: %w", err)
+	}
+	return nil
+}
+
+// selected is the name of the profile chosen by --profile, $GOOSECTL_PROFILE or the file
+func (f *profileFile) selected(name string) string {
+	if name != "" {
+		return name
+	}
+	if name := os.Getenv("GOOSECTL_PROFILE"); name != "" {
+		return name
+	}
+	if f.Current != "" {
+		return f.Current
+	}
+	return defaultProfile
+}
+
+func (f *profileFile) names() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/api/gen/go/v1/v1connect"
+)
+
+const validKey = "gsk_valid"
+
+// authService accepts validKey and counts logouts
+type authService struct {
+	v1connect.UnimplementedAuthServiceHandler
+	logouts atomic.Int32
+}
+
+func (s *authService) GetCurrentUser(ctx context.Context, req *connect.Request[v1.GetCurrentUserRequest]) (*connect.Response[v1.GetCurrentUserResponse], error) {
+	if req.Header().Get("Authorization") != "Bearer "+validKey {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("invalid API key"))
+	}
+	return connect.NewResponse(&v1.GetCurrentUserResponse{User: &v1.User{Email: "user@example.com"}}), nil
+}
+
+func (s *authService) Logout(ctx context.Context, req *connect.Request[v1.LogoutRequest]) (*connect.Response[v1.LogoutResponse], error) {
+	s.logouts.Add(1)
+	return connect.NewResponse(&v1.LogoutResponse{Success: true}), nil
+}
+
+// isolate points goosectl at a profile file in a new directory, which doesn't
+// exist yet, and clears the environment overrides; it returns the file path
+func isolate(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "goose", "goosectl.yaml")
+	t.Setenv("GOOSECTL_CONFIG", path)
+	for _, name := range []string{"GOOSECTL_PROFILE", "GOOSECTL_SERVER", "GOOSECTL_API_KEY"} {
+		t.Setenv(name, "")
+	}
+	return path
+}
+
+func load(t *testing.T) *profileFile {
+	t.Helper()
+
+	f, err := loadProfiles()
+	if err != nil {
+		t.Fatalf("loadProfiles: %v", err)
+	}
+	return f
+}
+
+func TestProfileFileRoundTrip(t *testing.T) {
+	path := isolate(t)
+
+	f := load(t)
+	if len(f.Profiles) != 0 || f.Current != "" {
+		t.Fatalf("missing file loaded as %+v, want no profiles", f)
+	}
+	f.Current = "work"
+	f.Profiles["work"] = &profile{Server: "https://goose.example.com", APIKey: "gsk_work"}
+	f.Profiles["home"] = &profile{Server: "http://localhost:8080", Token: "jwt"}
+	if err := f.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// the file holds credentials, so only the owner may read it
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("profile file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || info.Mode().Perm() != 0o700 {
+		t.Errorf("profile directory mode = %v, %v; want 0700", info.Mode().Perm(), err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	got := load(t)
+	if got.Current != "work" || strings.Join(got.names(), ",") != "home,work" {
+		t.Fatalf("reloaded %+v", got)
+	}
+	if *got.Profiles["work"] != *f.Profiles["work"] || *got.Profiles["home"] != *f.Profiles["home"] {
+		t.Errorf("reloaded profiles %+v, %+v", got.Profiles["work"], got.Profiles["home"])
+	}
+}
+
+func TestLoadProfilesInvalid(t *testing.T) {
+	path := isolate(t)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("profiles: [not, a, map]\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := loadProfiles(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("loadProfiles = %v, want a parse error naming the file", err)
+	}
+}
+
+func TestSelectedProfile(t *testing.T) {
+	isolate(t)
+	f := &profileFile{}
+
+	if got := f.selected(""); got != defaultProfile {
+		t.Errorf("nothing selected: %q, want %q", got, defaultProfile)
+	}
+	f.Current = "work"
+	if got := f.selected(""); got != "work" {
+		t.Errorf("current profile: %q, want work", got)
+	}
+	t.Setenv("GOOSECTL_PROFILE", "staging")
+	if got := f.selected(""); got != "staging" {
+		t.Errorf("$GOOSECTL_PROFILE: %q, want staging", got)
+	}
+	if got := f.selected("home"); got != "home" {
+		t.Errorf("--profile: %q, want home", got)
+	}
+}
+
+func newAuthServer(t *testing.T) (*httptest.Server, *authService) {
+	t.Helper()
+
+	service := &authService{}
+	mux := http.NewServeMux()
+	mux.Handle(v1connect.NewAuthServiceHandler(service))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, service
+}
+
+func TestLoginStoresCredentials(t *testing.T) {
+	isolate(t)
+	server, _ := newAuthServer(t)
+
+	if err := run(t.Context(), []string{"--server", server.URL, "--profile", "work", "login", "--api-key", validKey}); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	f := load(t)
+	if p := f.Profiles["work"]; p == nil || p.Server != server.URL || p.APIKey != validKey || p.Token != "" {
+		t.Fatalf("work profile = %+v", p)
+	}
+	// the first login selects its profile
+	if f.Current != "work" {
+		t.Errorf("current profile %q, want work", f.Current)
+	}
+
+	// credentials the server rejects are not saved
+	err := run(t.Context(), []string{"--profile", "work", "login", "--api-key", "gsk_revoked"})
+	if connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Fatalf("login with a rejected key = %v, want unauthenticated", err)
+	}
+	if p := load(t).Profiles["work"]; p.APIKey != validKey {
+		t.Errorf("rejected login replaced the key with %q", p.APIKey)
+	}
+
+	err = run(t.Context(), []string{"--profile", "home", "login", "--api-key", validKey})
+	if err == nil || !strings.Contains(err.Error(), "no server") {
+		t.Errorf("login without a server = %v", err)
+	}
+	if _, ok := load(t).Profiles["home"]; ok {
+		t.Error("failed login saved an empty profile")
+	}
+}
+
+func TestLogoutForgetsCredentials(t *testing.T) {
+	isolate(t)
+	server, service := newAuthServer(t)
+
+	f := load(t)
+	f.Current = "work"
+	f.Profiles["work"] = &profile{Server: server.URL, APIKey: validKey}
+	f.Profiles["session"] = &profile{Server: server.URL, Token: "jwt"}
+	if err := f.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// API keys stay valid, so logging out of one doesn't call the server
+	if err := run(t.Context(), []string{"logout"}); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if service.logouts.Load() != 0 {
+		t.Error("logout of an API key profile ended a session on the server")
+	}
+	if err := run(t.Context(), []string{"--profile", "session", "logout"}); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if service.logouts.Load() != 1 {
+		t.Errorf("server logouts = %d, want 1 for the session profile", service.logouts.Load())
+	}
+
+	f = load(t)
+	for _, name := range []string{"work", "session"} {
+		if p := f.Profiles[name]; p.APIKey != "" || p.Token != "" || p.Server != server.URL {
+			t.Errorf("%s profile after logout = %+v, want only the server", name, p)
+		}
+	}
+}
+
+func TestProfilesUseAndDelete(t *testing.T) {
+	isolate(t)
+	f := load(t)
+	f.Current = "work"
+	f.Profiles["work"] = &profile{Server: "https://goose.example.com", APIKey: "gsk_work"}
+	f.Profiles["home"] = &profile{Server: "http://localhost:8080"}
+	if err := f.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if err := run(t.Context(), []string{"profiles", "use", "home"}); err != nil {
+		t.Fatalf("profiles use: %v", err)
+	}
+	if got := load(t).Current; got != "home" {
+		t.Errorf("current profile %q, want home", got)
+	}
+	if err := run(t.Context(), []string{"profiles", "use", "missing"}); err == nil {
+		t.Error("profiles use of a missing profile succeeded")
+	}
+
+	if err := run(t.Context(), []string{"profiles", "delete", "home"}); err != nil {
+		t.Fatalf("profiles delete: %v", err)
+	}
+	f = load(t)
+	if _, ok := f.Profiles["home"]; ok || f.Current != "" {
+		t.Errorf("after deleting the current profile: %+v", f)
+	}
+	if f.Profiles["work"] == nil || f.Profiles["work"].APIKey != "gsk_work" {
+		t.Errorf("deleting home changed work: %+v", f.Profiles["work"])
+	}
+}
+
+func TestProfileSummaryHidesCredentials(t *testing.T) {
+	a := &app{
+		profiles:    &profileFile{Profiles: map[string]*profile{"work": {Server: "https://goose.example.com", APIKey: "gsk_secret"}}},
+		profileName: "work",
+	}
+	summary := profileSummary(a, "work")
+	if summary["credentials"] != "api key" || summary["current"] != true {
+		t.Errorf("summary = %v", summary)
+	}
+	for key, value := range summary {
+		if s, ok := value.(string); ok && strings.Contains(s, "gsk_secret") {
+			t.Errorf("summary %s exposes the API key", key)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// protoc-gen-openapi writes the OpenAPI document of the services in a proto
+// package, together with a Go package that embeds it: v1/v1openapi for v1.
+// Run it with strategy: all so that one document covers every file.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/damejeras/goose/internal/openapi"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func main() {
+	protogen.Options{}.Run(func(plugin *protogen.Plugin) error {
+		var files []protoreflect.FileDescriptor
+		var sources []string
+		var first *protogen.File
+		for _, file := range plugin.Files {
+			if !file.Generate || len(file.Services) == 0 {
+				continue
+			}
+			if first == nil {
+				first = file
+			} else if file.GoImportPath != first.GoImportPath {
+				return fmt.Errorf("%s and %s are in different Go packages; generate them separately", first.Desc.Path(), file.Desc.Path())
+			}
+			files = append(files, file.Desc)
+			sources = append(sources, file.Desc.Path())
+		}
+		if first == nil {
+			return nil
+		}
+
+		doc, err := openapi.Generate(openapi.Info{
+			Title:   "goose API",
+			Version: string(first.Desc.Package()),
+			Description: "Every procedure can be called with Connect, gRPC or gRPC-Web at /<service>/<method>. " +
+				"Procedures with a REST route can also be called with plain HTTP and JSON.",
+		}, files...)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		packageName := string(first.GoPackageName) + "openapi"
+		dir := path.Join(path.Dir(first.GeneratedFilenamePrefix), packageName)
+		importPath := protogen.GoImportPath(path.Join(string(first.GoImportPath), packageName))
+
+		spec := plugin.NewGeneratedFile(path.Join(dir, "openapi.json"), importPath)
+		spec.Write(append(data, '\n'))
+
+		g := plugin.NewGeneratedFile(path.Join(dir, "openapi.go"), importPath)
+		g.P("// Code generated by protoc-gen-openapi. DO NOT EDIT.")
+		g.P("//")
+		g.P("// Source: ", strings.Join(sources, ", "))
+		g.P()
+		g.P("// Package ", packageName, " embeds the OpenAPI document of the ", first.Desc.Package(), " services")
+		g.P("package ", packageName)
+		g.P()
+		g.P(`import _ "embed"`)
+		g.P()
+		g.P("// Document is the OpenAPI 3.1 document in JSON")
+		g.P("//")
+		g.P("//go:embed openapi.json")
+		g.P("var Document []byte")
+		return nil
+	})
+}
@@ -3,122 +3,467 @@ package main
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"flag"
+	"fmt"
 	"io"
-	"log"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/http/pprof"
 	"net/url"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"connectrpc.com/connect"
+	"connectrpc.com/grpcreflect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
 	"github.com/damejeras/goose/api/gen/go/v1/v1connect"
+	"github.com/damejeras/goose/api/gen/go/v1/v1openapi"
 	"github.com/damejeras/goose/db"
 	"github.com/damejeras/goose/db/sqlc"
 	"github.com/damejeras/goose/frontend"
+	"github.com/damejeras/goose/internal/apierror"
 	"github.com/damejeras/goose/internal/apikey"
 	"github.com/damejeras/goose/internal/auth"
-	"golang.org/x/net/http2"
-	"golang.org/x/net/http2/h2c"
+	"github.com/damejeras/goose/internal/certs"
+	"github.com/damejeras/goose/internal/config"
+	"github.com/damejeras/goose/internal/deviceauth"
+	"github.com/damejeras/goose/internal/docs"
+	"github.com/damejeras/goose/internal/headers"
+	"github.com/damejeras/goose/internal/health"
+	"github.com/damejeras/goose/internal/idempotency"
+	"github.com/damejeras/goose/internal/listener"
+	"github.com/damejeras/goose/internal/logging"
+	"github.com/damejeras/goose/internal/metrics"
+	"github.com/damejeras/goose/internal/recovery"
+	"github.com/damejeras/goose/internal/rest"
+	"github.com/damejeras/goose/internal/tracing"
+	"github.com/damejeras/goose/internal/validation"
+	// Register the reflection services so their auth policies can be checked
+	_ "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	_ "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
 func main() {
-	// Parse command line flags
-	googleClientID := flag.String("google-client-id", os.Getenv("GOOGLE_CLIENT_ID"), "Google OAuth client ID")
-	jwtSecretStr := flag.String("jwt-secret", os.Getenv("JWT_SECRET"), "JWT secret (base64 encoded)")
-	dbPath := flag.String("db", "storage/goose.db", "Database path")
-	port := flag.String("port", "8080", "Server port")
-	devMode := flag.Bool("dev", false, "Enable development mode with Vite proxy")
-	viteURL := flag.String("vite-url", "http://localhost:5173", "Vite dev server URL")
-	flag.Parse()
+	// Load configuration from file, environment and flags
+	cfg, opts, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	if opts.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to print configuration: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Setup logger
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
+	logLevel, _ := cfg.Log.SlogLevel()
+	logger := slog.New(tracing.NewLogHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: logLevel,
+	})))
+	for _, warning := range opts.Warnings {
+		logger.Warn("configuration warning", "warning", warning)
+	}
+
+	// Stop on SIGINT/SIGTERM; run drains in-flight requests before returning
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Validate required config
-	if *googleClientID == "" {
-		logger.Error("GOOGLE_CLIENT_ID is required")
+	if err := run(ctx, cfg, logger); err != nil {
+		logger.Error("server failed", "error", err)
 		os.Exit(1)
 	}
+	logger.Info("server stopped")
+}
 
+// run starts the server and blocks until ctx is cancelled, then shuts down in order:
+// readiness off, HTTP drain, background workers, database.
+func run(ctx context.Context, cfg config.Config, logger *slog.Logger) error {
 	var jwtSecret []byte
 	var err error
-	if *jwtSecretStr != "" {
-		jwtSecret, err = base64.StdEncoding.DecodeString(*jwtSecretStr)
-		if err != nil {
-			logger.Error("failed to decode JWT secret", "error", err)
-			os.Exit(1)
-		}
+	if cfg.Auth.JWTSecret != "" {
+		// Already validated by config.Load
+		jwtSecret, _ = base64.StdEncoding.DecodeString(cfg.Auth.JWTSecret)
 	} else {
 		// generateKey random secret for development
 		jwtSecret, err = auth.GenerateRandomSecret()
 		if err != nil {
-			logger.Error("failed to generate JWT secret", "error", err)
-			os.Exit(1)
+			return fmt.Errorf("generate JWT secret: %w", err)
 		}
 		logger.Warn("using randomly generated JWT secret - tokens will not persist across restarts")
 	}
 
+	signupPolicy := cfg.SignupPolicy()
+	logger.Info("signup policy configured", "mode", signupPolicy.Mode, "allowed_domains", signupPolicy.AllowedDomains, "allowed_hosted_domains", signupPolicy.AllowedHostedDomains)
+
+	tracer, err := tracing.Setup(ctx, cfg.TracingConfig(), "goose")
+	if err != nil {
+		return fmt.Errorf("set up tracing: %w", err)
+	}
+	defer func() {
+		// Flush buffered spans after everything else has shut down
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracer.Shutdown(flushCtx); err != nil {
+			logger.Error("failed to flush traces", "error", err)
+		}
+	}()
+
 	// Open database
-	database, err := db.Open(context.Background(), logger, *dbPath)
+	database, err := db.Open(ctx, logger, cfg.Database.Path)
 	if err != nil {
-		logger.Error("failed to open database", "error", err)
-		os.Exit(1)
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			logger.Error("failed to close database", "error", err)
+		}
+	}()
+
+	queries := sqlc.New(tracing.WrapDB(database))
+
+	// Invite the configured emails so invite-only servers can be bootstrapped
+	if err := auth.Invite(ctx, queries, cfg.Auth.Signup.Invitations...); err != nil {
+		return fmt.Errorf("create invitations: %w", err)
+	}
+	if signupPolicy.Mode == auth.SignupInviteOnly {
+		logger.Info("signup invitations configured", "count", len(cfg.Auth.Signup.Invitations))
 	}
-	defer database.Close()
 
-	queries := sqlc.New(database)
+	// Interceptors shared by every API handler, outermost first
+	tracingInterceptor, err := tracer.Interceptor()
+	if err != nil {
+		return err
+	}
+	interceptors := []connect.Interceptor{tracingInterceptor, logging.NewInterceptor(logger)}
+	var apiKeyUsage apikey.UsageRecorder
+	var serverMetrics *metrics.Metrics
+	var panicReporters []recovery.Reporter
+	if cfg.Metrics.Enabled {
+		serverMetrics = metrics.New(database)
+		interceptors = append(interceptors, serverMetrics.Interceptor())
+		apiKeyUsage = serverMetrics
+		panicReporters = append(panicReporters, serverMetrics)
+	}
+	// Recover panics inside the metrics interceptor so they are counted as internal errors
+	recoverer := recovery.New(logger, panicReporters...)
+	interceptors = append(interceptors, recoverer.Interceptor())
+	// Convert domain errors to Connect errors with details, logging and
+	// redacting their causes; everything below may return *apierror.Error
+	interceptors = append(interceptors, apierror.NewInterceptor(logger))
 
 	authService := auth.NewService(auth.Config{
-		GoogleClientID: *googleClientID,
+		GoogleClientID: cfg.Auth.GoogleClientID,
 		JWTSecret:      jwtSecret,
-		JWTExpiration:  24 * time.Hour,
+		JWTExpiration:  cfg.Auth.JWTExpiration,
+		Signup:         signupPolicy,
+		Cookies: auth.CookieConfig{
+			Enabled: cfg.Auth.Cookies.Enabled,
+			Domain:  cfg.Auth.Cookies.Domain,
+		},
+		AdminEmails: cfg.Auth.AdminEmails,
 	})
 
-	// Create auth interceptor - specify public methods that don't require auth
-	publicMethods := []string{
-		"/api.v1.AuthService/Login",
-		"/api.v1.GreeterService/SayHello", // Keep greeter public for testing
+	// Load access policies declared with the (api.v1.auth) method option
+	policies, err := auth.LoadMethodPolicies(v1.File_v1_auth_proto, v1.File_v1_apikey_proto, v1.File_v1_device_proto)
+	if err != nil {
+		return fmt.Errorf("load method auth policies: %w", err)
+	}
+	if cfg.Reflection.Enabled {
+		reflectionPolicy := auth.MethodPolicy{Public: true}
+		if cfg.Reflection.RequireAdmin {
+			reflectionPolicy = auth.MethodPolicy{Roles: []v1.Role{v1.Role_ROLE_ADMIN}}
+		}
+		for _, service := range []string{grpcreflect.ReflectV1ServiceName, grpcreflect.ReflectV1AlphaServiceName} {
+			policies["/"+service+"/ServerReflectionInfo"] = reflectionPolicy
+		}
+	}
+	// Services with a verified client certificate act as the user mapped to its common name
+	var servicePrincipals auth.ServicePrincipalResolver
+	if principals, _ := cfg.ServicePrincipals(); len(principals) > 0 {
+		servicePrincipals = auth.NewServicePrincipals(principals, queries)
+	}
+	authInterceptor := auth.NewInterceptor(authService, apikey.NewValidator(queries, logger, apiKeyUsage), servicePrincipals, policies)
+	interceptors = append(interceptors, authInterceptor)
+
+	// Enforce the buf.validate constraints on request messages, after auth so
+	// unauthenticated callers learn nothing about the request schema
+	validator, err := validation.New(v1.File_v1_auth_proto, v1.File_v1_apikey_proto, v1.File_v1_device_proto)
+	if err != nil {
+		return fmt.Errorf("compile request validation rules: %w", err)
 	}
-	authInterceptor := auth.NewInterceptor(authService, publicMethods)
+	interceptors = append(interceptors, validation.NewInterceptor(validator))
+
+	// Replay responses to retried mutations that carry an Idempotency-Key;
+	// keys are unique per user, and stored responses are encrypted under the JWT secret
+	idempotent := idempotency.NewInterceptor(queries, logger, idempotency.Config{
+		TTL:         cfg.Idempotency.TTL,
+		LockTimeout: cfg.Idempotency.LockTimeout,
+		Secret:      jwtSecret,
+		Scope: func(ctx context.Context) (string, bool) {
+			userID, ok := auth.GetUserIDFromContext(ctx)
+			return strconv.FormatInt(userID, 10), ok
+		},
+	})
+	interceptors = append(interceptors, idempotent)
+
+	// Probes and health checks; every status starts out not serving
+	healthChecker := health.NewChecker([]health.Probe{
+		{Name: "database", Check: database.PingContext},
+		{Name: "migrations", Check: func(ctx context.Context) error { return db.CheckMigrations(ctx, database) }},
+	}, v1connect.AuthServiceName, v1connect.APIKeyServiceName, v1connect.DeviceAuthServiceName)
 
-	// Setup HTTP mux
+	// The public mux serves only the API and the SPA. Internal endpoints go to
+	// the admin mux, which is the public one unless admin.listen is set.
 	mux := http.NewServeMux()
+	adminMux := mux
+	if cfg.Admin.Listen != "" {
+		adminMux = http.NewServeMux()
+	}
+
+	// Liveness and readiness probes, and grpc.health.v1.Health for gRPC load balancers.
+	// These bypass the auth interceptor.
+	adminMux.Handle("GET /healthz", health.LivenessHandler())
+	adminMux.Handle("GET /readyz", healthChecker.ReadinessHandler(logger))
+	adminMux.Handle(health.NewHandler(healthChecker))
+
+	// handle mounts a Connect handler served behind the auth interceptor and
+	// records its path, so that every procedure it serves is checked for a
+	// policy once all handlers are mounted
+	var guarded []string
+	handle := func(mux *http.ServeMux, path string, handler http.Handler) {
+		mux.Handle(path, handler)
+		guarded = append(guarded, path)
+	}
 
 	// Register auth service with interceptor
 	authPath, authHandler := v1connect.NewAuthServiceHandler(
 		auth.NewServer(authService, queries, logger),
-		connect.WithInterceptors(authInterceptor),
+		connect.WithInterceptors(interceptors...),
 	)
-
-	mux.Handle(authPath, authHandler)
+	handle(mux, authPath, authHandler)
 
 	// Register API key service with interceptor (requires authentication)
 	apiKeyPath, apiKeyHandler := v1connect.NewAPIKeyServiceHandler(
-		apikey.NewServer(queries, logger),
-		connect.WithInterceptors(authInterceptor),
+		apikey.NewServer(queries, logger, policies.Scopes()),
+		connect.WithInterceptors(interceptors...),
+	)
+	handle(mux, apiKeyPath, apiKeyHandler)
+
+	// Register the device authorization grant used by goosectl and other tools without a browser
+	deviceAuth := deviceauth.NewServer(authService, queries, logger, cfg.DeviceAuthConfig())
+	devicePath, deviceHandler := v1connect.NewDeviceAuthServiceHandler(
+		deviceAuth,
+		connect.WithInterceptors(interceptors...),
 	)
-	mux.Handle(apiKeyPath, apiKeyHandler)
+	handle(mux, devicePath, deviceauth.BaseURLMiddleware(deviceHandler))
+
+	// The OpenAPI document generated from the protos, and a page that renders it
+	if cfg.Docs.Enabled {
+		docs.Register(mux, v1openapi.Document)
+	}
+
+	// Server reflection lets grpcurl and Postman discover the services without the .proto files
+	if cfg.Reflection.Enabled {
+		reflector := grpcreflect.NewStaticReflector(
+			v1connect.AuthServiceName,
+			v1connect.APIKeyServiceName,
+			v1connect.DeviceAuthServiceName,
+			health.ServiceName,
+		)
+		reflectPath, reflectHandler := grpcreflect.NewHandlerV1(reflector, connect.WithInterceptors(interceptors...))
+		handle(adminMux, reflectPath, reflectHandler)
+		reflectAlphaPath, reflectAlphaHandler := grpcreflect.NewHandlerV1Alpha(reflector, connect.WithInterceptors(interceptors...))
+		handle(adminMux, reflectAlphaPath, reflectAlphaHandler)
+		logger.Info("server reflection enabled", "require_admin", cfg.Reflection.RequireAdmin)
+	}
+
+	// Fail startup, rather than every call, when a mounted procedure has no policy
+	if err := policies.Check(guarded...); err != nil {
+		return fmt.Errorf("check method auth policies: %w", err)
+	}
+
+	// Prometheus metrics
+	if serverMetrics != nil {
+		adminMux.Handle("GET /metrics", serverMetrics.Handler())
+	}
+
+	// Profiling exposes heap contents and can stall the process, so it is
+	// never served on the public port
+	if adminMux != mux {
+		adminMux.HandleFunc("/debug/pprof/", pprof.Index)
+		adminMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		adminMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		adminMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		adminMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
 
 	// Setup frontend handler - proxy to Vite in dev mode, serve static files in production
 	// Use "/{path...}" pattern to match all remaining requests (catch-all)
-	if *devMode {
-		logger.Info("development mode enabled", "vite_url", *viteURL)
-		mux.Handle("/{path...}", newViteProxy(*viteURL, logger))
+	if cfg.Dev.Enabled {
+		logger.Info("development mode enabled", "vite_url", cfg.Dev.ViteURL)
+		mux.Handle("/{path...}", newViteProxy(cfg.Dev.ViteURL, logger))
 	} else {
 		mux.Handle("/{path...}", frontend.Handler())
 	}
 
-	addr := ":" + *port
-	logger.Info("server starting", "addr", addr)
-	if err := http.ListenAndServe(addr, h2c.NewHandler(mux, &http2.Server{})); err != nil {
-		log.Fatal(err)
+	// Background workers run until the HTTP server has drained
+	workers := newWorkerGroup()
+	defer workers.Stop()
+	workers.Go(func(ctx context.Context) { idempotent.RunCleanup(ctx, time.Hour) })
+	workers.Go(func(ctx context.Context) { deviceAuth.RunCleanup(ctx, cfg.Auth.Device.CodeTTL) })
+
+	// RESTful routes declared with google.api.http, transcoded to the handlers on mux;
+	// everything else passes through to mux
+	transcoder, err := rest.New(mux, v1.File_v1_auth_proto, v1.File_v1_apikey_proto, v1.File_v1_device_proto)
+	if err != nil {
+		return fmt.Errorf("compile REST routes: %w", err)
+	}
+
+	// Security and CORS headers go on API and SPA responses alike
+	publicHandler := auth.ClientCertificateMiddleware(transcoder)
+	if corsConfig := cfg.CORSConfig(); corsConfig.Enabled() {
+		publicHandler = headers.CORS(corsConfig)(publicHandler)
+		logger.Info("CORS enabled", "allowed_origins", corsConfig.AllowedOrigins, "allow_credentials", corsConfig.AllowCredentials)
+	}
+	publicHandler = headers.Security(cfg.SecurityHeadersConfig())(publicHandler)
+
+	// Serve HTTP/1.1 and HTTP/2 natively, so that Shutdown also drains HTTP/2
+	// connections; without TLS HTTP/2 is served in cleartext (h2c)
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	srv := &http.Server{
+		Handler:           logging.Middleware(logger)(recoverer.Middleware(publicHandler)),
+		Protocols:         &protocols,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Terminate TLS with certificates that are reloaded without a restart
+	if tlsConfig := cfg.CertsConfig(); tlsConfig.Enabled() {
+		reloader, err := certs.NewReloader(tlsConfig, logger)
+		if err != nil {
+			return fmt.Errorf("load TLS certificate: %w", err)
+		}
+		workers.Go(reloader.Watch)
+		protocols.SetUnencryptedHTTP2(false)
+		protocols.SetHTTP2(true)
+		srv.TLSConfig = reloader.TLSConfig()
+		logger.Info("TLS enabled", "client_ca", tlsConfig.ClientCAFile != "", "client_auth", tlsConfig.ClientAuth)
+	}
+
+	servers := []namedServer{{name: "public", addresses: cfg.ListenAddresses(), Server: srv}}
+	if adminMux != mux {
+		var adminProtocols http.Protocols
+		adminProtocols.SetHTTP1(true)
+		adminProtocols.SetUnencryptedHTTP2(true)
+		servers = append(servers, namedServer{name: "admin", addresses: []string{cfg.Admin.Listen}, Server: &http.Server{
+			Handler:           logging.Middleware(logger)(recoverer.Middleware(adminMux)),
+			Protocols:         &adminProtocols,
+			ReadHeaderTimeout: 10 * time.Second,
+		}})
 	}
+
+	// Open every listener before serving, so a taken port fails startup
+	addresses := make([][]string, len(servers))
+	for i, s := range servers {
+		addresses[i] = s.addresses
+	}
+	listeners, err := listener.Open(addresses...)
+	if err != nil {
+		return err
+	}
+
+	serveErr := make(chan error, len(slices.Concat(listeners...)))
+	for i, s := range servers {
+		// Serve fills in TLSConfig for HTTP/2, so decide before starting any listener
+		useTLS := s.TLSConfig != nil
+		for _, ln := range listeners[i] {
+			go func() {
+				logger.Info("server starting", "server", s.name, "addr", ln.Addr().String(), "network", ln.Addr().Network(), "tls", useTLS)
+				if useTLS {
+					serveErr <- s.ServeTLS(ln, "", "")
+					return
+				}
+				serveErr <- s.Serve(ln)
+			}()
+		}
+	}
+	healthChecker.SetServing(true)
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Stop advertising readiness and give load balancers time to notice
+	logger.Info("shutting down", "shutdown_delay", cfg.Server.ShutdownDelay.String(), "drain_timeout", cfg.Server.DrainTimeout.String())
+	healthChecker.Shutdown()
+	srv.SetKeepAlivesEnabled(false)
+	time.Sleep(cfg.Server.ShutdownDelay)
+
+	// Drain in-flight requests
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.DrainTimeout)
+	defer cancel()
+	for _, s := range servers {
+		if err := s.Shutdown(drainCtx); err != nil {
+			logger.Error("failed to drain connections", "server", s.name, "error", err)
+			s.Close()
+		}
+	}
+
+	workers.Stop()
+
+	return nil
+}
+
+// namedServer is an HTTP server with the addresses it listens on
+type namedServer struct {
+	*http.Server
+	name      string
+	addresses []string
+}
+
+// workerGroup runs background workers that are stopped together during shutdown
+type workerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newWorkerGroup() *workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workerGroup{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a goroutine; fn must return once its context is cancelled
+func (g *workerGroup) Go(fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn(g.ctx)
+	}()
+}
+
+// Stop cancels every worker and waits for them to return
+func (g *workerGroup) Stop() {
+	g.cancel()
+	g.wg.Wait()
 }
 
 // newViteProxy creates a proxy handler that forwards requests to the Vite dev server.
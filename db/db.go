@@ -6,6 +6,7 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -59,3 +60,58 @@ func Open(ctx context.Context, logger *slog.Logger, path string) (c *sql.DB, rer
 
 	return db, nil
 }
+
+// ErrMigrationsPending is returned by CheckMigrations when the schema is behind the embedded migrations
+var ErrMigrationsPending = errors.New("database migrations pending")
+
+// ErrMigrationDirty is returned by CheckMigrations when a migration failed part way
+var ErrMigrationDirty = errors.New("database migration dirty")
+
+// CheckMigrations reports whether the schema is at the latest embedded migration
+func CheckMigrations(ctx context.Context, db *sql.DB) error {
+	latest, err := latestMigration()
+	if err != nil {
+		return err
+	}
+
+	var version uint
+	var dirty bool
+	err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: no migrations applied, latest is %d", ErrMigrationsPending, latest)
+	}
+	if err != nil {
+		return fmt.Errorf("read migration version: %w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("%w: version %d", ErrMigrationDirty, version)
+	}
+	if version < latest {
+		return fmt.Errorf("%w: at version %d, latest is %d", ErrMigrationsPending, version, latest)
+	}
+	return nil
+}
+
+func latestMigration() (uint, error) {
+	source, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("create migration source: %w", err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("read first migration: %w", err)
+	}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("read next migration: %w", err)
+		}
+		version = next
+	}
+}
@@ -0,0 +1,5 @@
+create table if not exists invitations (
+    email text primary key,
+    created_at datetime not null default current_timestamp,
+    accepted_at datetime
+);
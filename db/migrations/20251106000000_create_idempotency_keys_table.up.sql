@@ -0,0 +1,12 @@
+create table if not exists idempotency_keys (
+    scope text not null,
+    key_hash blob not null,
+    fingerprint blob not null,
+    response blob,
+    created_at datetime not null,
+    locked_until datetime not null,
+    expires_at datetime not null,
+    primary key (scope, key_hash)
+);
+
+create index idx_idempotency_keys_expires_at on idempotency_keys(expires_at);
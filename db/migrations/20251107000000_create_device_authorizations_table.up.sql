@@ -0,0 +1,15 @@
+create table if not exists device_authorizations (
+    device_code_hash blob primary key,
+    user_code text not null unique,
+    client_name text not null,
+    -- pending, approved or denied
+    status text not null default 'pending',
+    user_id integer,
+    interval_seconds integer not null,
+    last_polled_at datetime,
+    created_at datetime not null,
+    expires_at datetime not null,
+    foreign key (user_id) references users(id) on delete cascade
+);
+
+create index idx_device_authorizations_expires_at on device_authorizations(expires_at);
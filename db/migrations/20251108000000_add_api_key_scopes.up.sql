@@ -0,0 +1,2 @@
+-- space-separated scopes the key is limited to; null for a key with full access
+alter table api_keys add column scopes text;
@@ -0,0 +1,9 @@
+-- unknown user codes entered by a user, to limit guessing (RFC 8628, section 5.1)
+create table if not exists device_code_failures (
+    id integer primary key,
+    user_id integer not null,
+    failed_at datetime not null,
+    foreign key (user_id) references users(id) on delete cascade
+);
+
+create index idx_device_code_failures_user_id_failed_at on device_code_failures(user_id, failed_at);
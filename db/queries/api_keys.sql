@@ -1,6 +1,6 @@
 -- name: CreateAPIKey :one
-insert into api_keys (id, user_id, name, key_hash, key_prefix, key_suffix, created_at)
-values (?, ?, ?, ?, ?, ?, current_timestamp)
+insert into api_keys (id, user_id, name, key_hash, key_prefix, key_suffix, scopes, created_at)
+values (?, ?, ?, ?, ?, ?, ?, current_timestamp)
 returning *;
 
 -- name: GetAPIKeyByHash :one
@@ -0,0 +1,58 @@
+-- name: CreateDeviceAuthorization :exec
+insert into device_authorizations (device_code_hash, user_code, client_name, interval_seconds, created_at, expires_at)
+values (?, ?, ?, ?, ?, ?);
+
+-- name: CountPendingDeviceAuthorizations :one
+select count(*) from device_authorizations
+where status = 'pending' and expires_at > ?;
+
+-- name: GetDeviceAuthorization :one
+select * from device_authorizations
+where device_code_hash = ?;
+
+-- name: GetPendingDeviceAuthorization :one
+select client_name from device_authorizations
+where user_code = sqlc.arg(user_code) and status = 'pending' and expires_at > sqlc.arg(now);
+
+-- name: RecordDeviceAuthorizationPoll :execrows
+update device_authorizations
+set last_polled_at = sqlc.arg(now)
+where device_code_hash = sqlc.arg(device_code_hash)
+  and (last_polled_at is null or last_polled_at <= sqlc.arg(polled_before));
+
+-- name: SlowDownDeviceAuthorization :exec
+update device_authorizations
+set interval_seconds = ?, last_polled_at = ?
+where device_code_hash = ?;
+
+-- name: ApproveDeviceAuthorization :one
+update device_authorizations
+set status = 'approved', user_id = sqlc.arg(user_id)
+where user_code = sqlc.arg(user_code) and status = 'pending' and expires_at > sqlc.arg(now)
+returning client_name;
+
+-- name: DenyDeviceAuthorization :execrows
+update device_authorizations
+set status = 'denied'
+where user_code = sqlc.arg(user_code) and status = 'pending' and expires_at > sqlc.arg(now);
+
+-- name: ConsumeDeviceAuthorization :execrows
+delete from device_authorizations
+where device_code_hash = ? and status = ?;
+
+-- name: DeleteExpiredDeviceAuthorizations :execrows
+delete from device_authorizations
+where expires_at <= ?;
+
+-- name: RecordDeviceCodeFailure :exec
+insert into device_code_failures (user_id, failed_at)
+values (?, ?);
+
+-- name: ListDeviceCodeFailures :many
+select failed_at from device_code_failures
+where user_id = sqlc.arg(user_id) and failed_at > sqlc.arg(since)
+order by failed_at;
+
+-- name: DeleteDeviceCodeFailures :execrows
+delete from device_code_failures
+where failed_at <= ?;
@@ -0,0 +1,28 @@
+-- name: ReserveIdempotencyKey :execrows
+insert into idempotency_keys (scope, key_hash, fingerprint, created_at, locked_until, expires_at)
+values (sqlc.arg(scope), sqlc.arg(key_hash), sqlc.arg(fingerprint), sqlc.arg(now), sqlc.arg(locked_until), sqlc.arg(expires_at))
+on conflict (scope, key_hash) do update
+set fingerprint = excluded.fingerprint,
+    response = null,
+    created_at = excluded.created_at,
+    locked_until = excluded.locked_until,
+    expires_at = excluded.expires_at
+where idempotency_keys.expires_at <= sqlc.arg(now)
+   or (idempotency_keys.response is null and idempotency_keys.locked_until <= sqlc.arg(now));
+
+-- name: GetIdempotencyKey :one
+select * from idempotency_keys
+where scope = ? and key_hash = ?;
+
+-- name: CompleteIdempotencyKey :exec
+update idempotency_keys
+set response = ?
+where scope = ? and key_hash = ?;
+
+-- name: ReleaseIdempotencyKey :exec
+delete from idempotency_keys
+where scope = ? and key_hash = ? and response is null;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+delete from idempotency_keys
+where expires_at <= ?;
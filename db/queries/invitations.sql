@@ -0,0 +1,13 @@
+-- name: GetPendingInvitationByEmail :one
+select * from invitations
+where email = ? and accepted_at is null;
+
+-- name: CreateInvitation :exec
+insert into invitations (email)
+values (?)
+on conflict (email) do nothing;
+
+-- name: AcceptInvitation :exec
+update invitations
+set accepted_at = current_timestamp
+where email = ?;
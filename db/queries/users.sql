@@ -1,6 +1,9 @@
 -- name: GetUser :one
 select * from users where id = ?;
 
+-- name: GetUserByEmail :one
+select * from users where lower(email) = lower(?);
+
 -- name: FindUserByGoogleID :one
 select * from users where google_id = ?;
 
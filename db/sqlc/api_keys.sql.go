@@ -7,12 +7,13 @@ package sqlc
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createAPIKey = `-- name: CreateAPIKey :one
-insert into api_keys (id, user_id, name, key_hash, key_prefix, key_suffix, created_at)
-values (?, ?, ?, ?, ?, ?, current_timestamp)
-returning id, user_id, name, key_hash, key_prefix, key_suffix, created_at, last_used_at
+insert into api_keys (id, user_id, name, key_hash, key_prefix, key_suffix, scopes, created_at)
+values (?, ?, ?, ?, ?, ?, ?, current_timestamp)
+returning id, user_id, name, key_hash, key_prefix, key_suffix, created_at, last_used_at, scopes
 `
 
 type CreateAPIKeyParams struct {
@@ -22,6 +23,7 @@ type CreateAPIKeyParams struct {
 	KeyHash   string
 	KeyPrefix string
 	KeySuffix string
+	Scopes    sql.NullString
 }
 
 func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
@@ -32,6 +34,7 @@ func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (Api
 		arg.KeyHash,
 		arg.KeyPrefix,
 		arg.KeySuffix,
+		arg.Scopes,
 	)
 	var i ApiKey
 	err := row.Scan(
@@ -43,6 +46,7 @@ func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (Api
 		&i.KeySuffix,
 		&i.CreatedAt,
 		&i.LastUsedAt,
+		&i.Scopes,
 	)
 	return i, err
 }
@@ -63,7 +67,7 @@ func (q *Queries) DeleteAPIKey(ctx context.Context, arg DeleteAPIKeyParams) erro
 }
 
 const getAPIKeyByHash = `-- name: GetAPIKeyByHash :one
-select id, user_id, name, key_hash, key_prefix, key_suffix, created_at, last_used_at from api_keys
+select id, user_id, name, key_hash, key_prefix, key_suffix, created_at, last_used_at, scopes from api_keys
 where key_hash = ?
 `
 
@@ -79,12 +83,13 @@ func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey,
 		&i.KeySuffix,
 		&i.CreatedAt,
 		&i.LastUsedAt,
+		&i.Scopes,
 	)
 	return i, err
 }
 
 const getAPIKeyByID = `-- name: GetAPIKeyByID :one
-select id, user_id, name, key_hash, key_prefix, key_suffix, created_at, last_used_at from api_keys
+select id, user_id, name, key_hash, key_prefix, key_suffix, created_at, last_used_at, scopes from api_keys
 where id = ? and user_id = ?
 `
 
@@ -105,12 +110,13 @@ func (q *Queries) GetAPIKeyByID(ctx context.Context, arg GetAPIKeyByIDParams) (A
 		&i.KeySuffix,
 		&i.CreatedAt,
 		&i.LastUsedAt,
+		&i.Scopes,
 	)
 	return i, err
 }
 
 const listAPIKeysByUserID = `-- name: ListAPIKeysByUserID :many
-select id, user_id, name, key_hash, key_prefix, key_suffix, created_at, last_used_at from api_keys
+select id, user_id, name, key_hash, key_prefix, key_suffix, created_at, last_used_at, scopes from api_keys
 where user_id = ?
 order by created_at desc
 `
@@ -133,6 +139,7 @@ func (q *Queries) ListAPIKeysByUserID(ctx context.Context, userID int64) ([]ApiK
 			&i.KeySuffix,
 			&i.CreatedAt,
 			&i.LastUsedAt,
+			&i.Scopes,
 		); err != nil {
 			return nil, err
 		}
@@ -162,7 +169,7 @@ const updateAPIKeyName = `-- name: UpdateAPIKeyName :one
 update api_keys
 set name = ?
 where id = ? and user_id = ?
-returning id, user_id, name, key_hash, key_prefix, key_suffix, created_at, last_used_at
+returning id, user_id, name, key_hash, key_prefix, key_suffix, created_at, last_used_at, scopes
 `
 
 type UpdateAPIKeyNameParams struct {
@@ -183,6 +190,7 @@ func (q *Queries) UpdateAPIKeyName(ctx context.Context, arg UpdateAPIKeyNamePara
 		&i.KeySuffix,
 		&i.CreatedAt,
 		&i.LastUsedAt,
+		&i.Scopes,
 	)
 	return i, err
 }
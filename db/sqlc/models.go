@@ -18,6 +18,41 @@ type ApiKey struct {
 	KeySuffix  string
 	CreatedAt  time.Time
 	LastUsedAt sql.NullTime
+	Scopes     sql.NullString
+}
+
+type DeviceAuthorization struct {
+	DeviceCodeHash  []byte
+	UserCode        string
+	ClientName      string
+	Status          string
+	UserID          sql.NullInt64
+	IntervalSeconds int64
+	LastPolledAt    sql.NullTime
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
+
+type DeviceCodeFailure struct {
+	ID       int64
+	UserID   int64
+	FailedAt time.Time
+}
+
+type IdempotencyKey struct {
+	Scope       string
+	KeyHash     []byte
+	Fingerprint []byte
+	Response    []byte
+	CreatedAt   time.Time
+	LockedUntil time.Time
+	ExpiresAt   time.Time
+}
+
+type Invitation struct {
+	Email      string
+	CreatedAt  time.Time
+	AcceptedAt sql.NullTime
 }
 
 type User struct {
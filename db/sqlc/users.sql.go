@@ -73,6 +73,25 @@ func (q *Queries) GetUser(ctx context.Context, id int64) (User, error) {
 	return i, err
 }
 
+const getUserByEmail = `-- name: GetUserByEmail :one
+select id, email, google_id, created_at, updated_at, last_login_at, name from users where lower(email) = lower(?)
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, lower string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, lower)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.GoogleID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.Name,
+	)
+	return i, err
+}
+
 const updateUserLastSeen = `-- name: UpdateUserLastSeen :exec
 update users set last_login_at = CURRENT_TIMESTAMP where id = ?
 `
@@ -36,8 +36,9 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   useEffect(() => {
     // Check if user is authenticated on mount
     const checkAuth = async () => {
+      // With cookie sessions there is no token, only the cached user
       const token = localStorage.getItem('auth_token')
-      if (!token) {
+      if (!token && !localStorage.getItem('auth_user')) {
         setIsLoading(false)
         return
       }
@@ -70,12 +71,14 @@ export function AuthProvider({ children }: { children: ReactNode }) {
         googleIdToken,
       })
 
-      if (!response.user || !response.jwt) {
+      if (!response.user) {
         throw new Error('Invalid login response')
       }
 
-      // Store JWT token
-      apiClient.setToken(response.jwt)
+      // Store JWT token (empty when the server uses cookie sessions)
+      if (response.jwt) {
+        apiClient.setToken(response.jwt)
+      }
 
       // Store user data
       const userData = convertApiUser(response.user)
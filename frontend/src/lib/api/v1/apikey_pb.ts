@@ -4,7 +4,10 @@
 
 import type { GenFile, GenMessage, GenService } from "@bufbuild/protobuf/codegenv2";
 import { fileDesc, messageDesc, serviceDesc } from "@bufbuild/protobuf/codegenv2";
+import { file_buf_validate_validate } from "../buf/validate/validate_pb";
+import { file_google_api_annotations } from "../google/api/annotations_pb";
 import { file_v1_common } from "./common_pb";
+import { file_v1_options } from "./options_pb";
 import type { Timestamp } from "@bufbuild/protobuf/wkt";
 import { file_google_protobuf_timestamp } from "@bufbuild/protobuf/wkt";
 import type { Message } from "@bufbuild/protobuf";
@@ -13,7 +16,7 @@ import type { Message } from "@bufbuild/protobuf";
  * Describes the file v1/apikey.proto.
  */
 export const file_v1_apikey: GenFile = /*@__PURE__*/
-  fileDesc("Cg92MS9hcGlrZXkucHJvdG8SBmFwaS52MSKYAQoGQVBJS2V5EgoKAmlkGAEgASgJEgwKBG5hbWUYAiABKAkSEgoKa2V5X21hc2tlZBgDIAEoCRIuCgpjcmVhdGVkX2F0GAQgASgLMhouZ29vZ2xlLnByb3RvYnVmLlRpbWVzdGFtcBIwCgxsYXN0X3VzZWRfYXQYBSABKAsyGi5nb29nbGUucHJvdG9idWYuVGltZXN0YW1wIiMKE0NyZWF0ZUFQSUtleVJlcXVlc3QSDAoEbmFtZRgBIAEoCSJtChRDcmVhdGVBUElLZXlSZXNwb25zZRIKCgJpZBgBIAEoCRIMCgRuYW1lGAIgASgJEgsKA2tleRgDIAEoCRIuCgpjcmVhdGVkX2F0GAQgASgLMhouZ29vZ2xlLnByb3RvYnVmLlRpbWVzdGFtcCIUChJMaXN0QVBJS2V5c1JlcXVlc3QiNwoTTGlzdEFQSUtleXNSZXNwb25zZRIgCghhcGlfa2V5cxgBIAMoCzIOLmFwaS52MS5BUElLZXkiIQoTRGVsZXRlQVBJS2V5UmVxdWVzdBIKCgJpZBgBIAEoCSInChREZWxldGVBUElLZXlSZXNwb25zZRIPCgdzdWNjZXNzGAEgASgIIi8KE1VwZGF0ZUFQSUtleVJlcXVlc3QSCgoCaWQYASABKAkSDAoEbmFtZRgCIAEoCSI3ChRVcGRhdGVBUElLZXlSZXNwb25zZRIfCgdhcGlfa2V5GAEgASgLMg4uYXBpLnYxLkFQSUtleTLAAgoNQVBJS2V5U2VydmljZRJLCgxDcmVhdGVBUElLZXkSGy5hcGkudjEuQ3JlYXRlQVBJS2V5UmVxdWVzdBocLmFwaS52MS5DcmVhdGVBUElLZXlSZXNwb25zZSIAEkgKC0xpc3RBUElLZXlzEhouYXBpLnYxLkxpc3RBUElLZXlzUmVxdWVzdBobLmFwaS52MS5MaXN0QVBJS2V5c1Jlc3BvbnNlIgASSwoMRGVsZXRlQVBJS2V5EhsuYXBpLnYxLkRlbGV0ZUFQSUtleVJlcXVlc3QaHC5hcGkudjEuRGVsZXRlQVBJS2V5UmVzcG9uc2UiABJLCgxVcGRhdGVBUElLZXkSGy5hcGkudjEuVXBkYXRlQVBJS2V5UmVxdWVzdBocLmFwaS52MS5VcGRhdGVBUElLZXlSZXNwb25zZSIAQipaKGdpdGh1Yi5jb20vZGFtZWplcmFzL2dvb3NlL2FwaS9nZW4vZ28vdjFiBnByb3RvMw", [file_v1_common, file_google_protobuf_timestamp]);
+  fileDesc("Cg92MS9hcGlrZXkucHJvdG8SBmFwaS52MSKoAQoGQVBJS2V5EgoKAmlkGAEgASgJEgwKBG5hbWUYAiABKAkSEgoKa2V5X21hc2tlZBgDIAEoCRIuCgpjcmVhdGVkX2F0GAQgASgLMhouZ29vZ2xlLnByb3RvYnVmLlRpbWVzdGFtcBIwCgxsYXN0X3VzZWRfYXQYBSABKAsyGi5nb29nbGUucHJvdG9idWYuVGltZXN0YW1wEg4KBnNjb3BlcxgGIAMoCSJdChNDcmVhdGVBUElLZXlSZXF1ZXN0EiwKBG5hbWUYASABKAlCHrpIG3IZEAEYZDITXlteXHgwMC1ceDFGXHg3Rl0rJBIYCgZzY29wZXMYAiADKAlCCLpIBZIBAhgBIn0KFENyZWF0ZUFQSUtleVJlc3BvbnNlEgoKAmlkGAEgASgJEgwKBG5hbWUYAiABKAkSCwoDa2V5GAMgASgJEi4KCmNyZWF0ZWRfYXQYBCABKAsyGi5nb29nbGUucHJvdG9idWYuVGltZXN0YW1wEg4KBnNjb3BlcxgFIAMoCSIUChJMaXN0QVBJS2V5c1JlcXVlc3QiNwoTTGlzdEFQSUtleXNSZXNwb25zZRIgCghhcGlfa2V5cxgBIAMoCzIOLmFwaS52MS5BUElLZXkiKwoTRGVsZXRlQVBJS2V5UmVxdWVzdBIUCgJpZBgBIAEoCUIIukgFcgOwAQEiJwoURGVsZXRlQVBJS2V5UmVzcG9uc2USDwoHc3VjY2VzcxgBIAEoCCJZChNVcGRhdGVBUElLZXlSZXF1ZXN0EhQKAmlkGAEgASgJQgi6SAVyA7ABARIsCgRuYW1lGAIgASgJQh66SBtyGRhkMhNeW15ceDAwLVx4MUZceDdGXSskEAEiNwoUVXBkYXRlQVBJS2V5UmVzcG9uc2USHwoHYXBpX2tleRgBIAEoCzIOLmFwaS52MS5BUElLZXky+gMKDUFQSUtleVNlcnZpY2USeAoMQ3JlYXRlQVBJS2V5EhsuYXBpLnYxLkNyZWF0ZUFQSUtleVJlcXVlc3QaHC5hcGkudjEuQ3JlYXRlQVBJS2V5UmVzcG9uc2UiLYq1GBIIAhIOYXBpX2tleXM6d3JpdGWC0+STAhEiDC92MS9hcGkta2V5czoBKhJ0CgtMaXN0QVBJS2V5cxIaLmFwaS52MS5MaXN0QVBJS2V5c1JlcXVlc3QaGy5hcGkudjEuTGlzdEFQSUtleXNSZXNwb25zZSIskAIBirUYEQgCEg1hcGlfa2V5czpyZWFkgtPkkwIOEgwvdjEvYXBpLWtleXMSegoMRGVsZXRlQVBJS2V5EhsuYXBpLnYxLkRlbGV0ZUFQSUtleVJlcXVlc3QaHC5hcGkudjEuRGVsZXRlQVBJS2V5UmVzcG9uc2UiL4q1GBIIAhIOYXBpX2tleXM6d3JpdGWC0+STAhMqES92MS9hcGkta2V5cy97aWR9En0KDFVwZGF0ZUFQSUtleRIbLmFwaS52MS5VcGRhdGVBUElLZXlSZXF1ZXN0GhwuYXBpLnYxLlVwZGF0ZUFQSUtleVJlc3BvbnNlIjKKtRgSCAISDmFwaV9rZXlzOndyaXRlgtPkkwIWMhEvdjEvYXBpLWtleXMve2lkfToBKkIqWihnaXRodWIuY29tL2RhbWVqZXJhcy9nb29zZS9hcGkvZ2VuL2dvL3YxYgZwcm90bzM", [file_buf_validate_validate, file_google_api_annotations, file_v1_common, file_v1_options, file_google_protobuf_timestamp]);
 
 /**
  * @generated from message api.v1.APIKey
@@ -45,6 +48,13 @@ export type APIKey = Message<"api.v1.APIKey"> & {
    * @generated from field: google.protobuf.Timestamp last_used_at = 5;
    */
   lastUsedAt?: Timestamp;
+
+  /**
+   * Scopes the key is limited to; empty for a key with full access
+   *
+   * @generated from field: repeated string scopes = 6;
+   */
+  scopes: string[];
 };
 
 /**
@@ -59,9 +69,20 @@ export const APIKeySchema: GenMessage<APIKey> = /*@__PURE__*/
  */
 export type CreateAPIKeyRequest = Message<"api.v1.CreateAPIKeyRequest"> & {
   /**
+   * Display name; no control characters
+   *
    * @generated from field: string name = 1;
    */
   name: string;
+
+  /**
+   * Limits the key to procedures requiring only these scopes, e.g. "api_keys:read";
+   * empty grants full access. Keys created with a scoped credential must be
+   * limited to scopes of that credential.
+   *
+   * @generated from field: repeated string scopes = 2;
+   */
+  scopes: string[];
 };
 
 /**
@@ -96,6 +117,11 @@ export type CreateAPIKeyResponse = Message<"api.v1.CreateAPIKeyResponse"> & {
    * @generated from field: google.protobuf.Timestamp created_at = 4;
    */
   createdAt?: Timestamp;
+
+  /**
+   * @generated from field: repeated string scopes = 5;
+   */
+  scopes: string[];
 };
 
 /**
@@ -179,6 +205,8 @@ export type UpdateAPIKeyRequest = Message<"api.v1.UpdateAPIKeyRequest"> & {
   id: string;
 
   /**
+   * Display name; no control characters
+   *
    * @generated from field: string name = 2;
    */
   name: string;
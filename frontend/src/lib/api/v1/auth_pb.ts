@@ -4,21 +4,26 @@
 
 import type { GenFile, GenMessage, GenService } from "@bufbuild/protobuf/codegenv2";
 import { fileDesc, messageDesc, serviceDesc } from "@bufbuild/protobuf/codegenv2";
+import { file_buf_validate_validate } from "../buf/validate/validate_pb";
+import { file_google_api_annotations } from "../google/api/annotations_pb";
 import type { User } from "./common_pb";
 import { file_v1_common } from "./common_pb";
+import { file_v1_options } from "./options_pb";
 import type { Message } from "@bufbuild/protobuf";
 
 /**
  * Describes the file v1/auth.proto.
  */
 export const file_v1_auth: GenFile = /*@__PURE__*/
-  fileDesc("Cg12MS9hdXRoLnByb3RvEgZhcGkudjEiJwoMTG9naW5SZXF1ZXN0EhcKD2dvb2dsZV9pZF90b2tlbhgBIAEoCSI4Cg1Mb2dpblJlc3BvbnNlEgsKA2p3dBgBIAEoCRIaCgR1c2VyGAIgASgLMgwuYXBpLnYxLlVzZXIiFwoVR2V0Q3VycmVudFVzZXJSZXF1ZXN0IjQKFkdldEN1cnJlbnRVc2VyUmVzcG9uc2USGgoEdXNlchgBIAEoCzIMLmFwaS52MS5Vc2VyIg8KDUxvZ291dFJlcXVlc3QiIQoOTG9nb3V0UmVzcG9uc2USDwoHc3VjY2VzcxgBIAEoCDLTAQoLQXV0aFNlcnZpY2USNgoFTG9naW4SFC5hcGkudjEuTG9naW5SZXF1ZXN0GhUuYXBpLnYxLkxvZ2luUmVzcG9uc2UiABJRCg5HZXRDdXJyZW50VXNlchIdLmFwaS52MS5HZXRDdXJyZW50VXNlclJlcXVlc3QaHi5hcGkudjEuR2V0Q3VycmVudFVzZXJSZXNwb25zZSIAEjkKBkxvZ291dBIVLmFwaS52MS5Mb2dvdXRSZXF1ZXN0GhYuYXBpLnYxLkxvZ291dFJlc3BvbnNlIgBCKlooZ2l0aHViLmNvbS9kYW1lamVyYXMvZ29vc2UvYXBpL2dlbi9nby92MWIGcHJvdG8z", [file_v1_common]);
+  fileDesc("Cg12MS9hdXRoLnByb3RvEgZhcGkudjEiMwoMTG9naW5SZXF1ZXN0EiMKD2dvb2dsZV9pZF90b2tlbhgBIAEoCUIKukgHcgUQARiAQCI4Cg1Mb2dpblJlc3BvbnNlEgsKA2p3dBgBIAEoCRIaCgR1c2VyGAIgASgLMgwuYXBpLnYxLlVzZXIiFwoVR2V0Q3VycmVudFVzZXJSZXF1ZXN0IjQKFkdldEN1cnJlbnRVc2VyUmVzcG9uc2USGgoEdXNlchgBIAEoCzIMLmFwaS52MS5Vc2VyIg8KDUxvZ291dFJlcXVlc3QiIQoOTG9nb3V0UmVzcG9uc2USDwoHc3VjY2VzcxgBIAEoCDK5AgoLQXV0aFNlcnZpY2USVQoFTG9naW4SFC5hcGkudjEuTG9naW5SZXF1ZXN0GhUuYXBpLnYxLkxvZ2luUmVzcG9uc2UiH4q1GAIIAYLT5JMCEyIOL3YxL2F1dGgvbG9naW46ASoSeAoOR2V0Q3VycmVudFVzZXISHS5hcGkudjEuR2V0Q3VycmVudFVzZXJSZXF1ZXN0Gh4uYXBpLnYxLkdldEN1cnJlbnRVc2VyUmVzcG9uc2UiJ5ACAYq1GA0IAhIJdXNlcjpyZWFkgtPkkwINEgsvdjEvYXV0aC9tZRJZCgZMb2dvdXQSFS5hcGkudjEuTG9nb3V0UmVxdWVzdBoWLmFwaS52MS5Mb2dvdXRSZXNwb25zZSIgirUYAggCgtPkkwIUIg8vdjEvYXV0aC9sb2dvdXQ6ASpCKlooZ2l0aHViLmNvbS9kYW1lamVyYXMvZ29vc2UvYXBpL2dlbi9nby92MWIGcHJvdG8z", [file_buf_validate_validate, file_google_api_annotations, file_v1_common, file_v1_options]);
 
 /**
  * @generated from message api.v1.LoginRequest
  */
 export type LoginRequest = Message<"api.v1.LoginRequest"> & {
   /**
+   * Google ID tokens are compact JWTs of a few kilobytes at most
+   *
    * @generated from field: string google_id_token = 1;
    */
   googleIdToken: string;
@@ -0,0 +1,297 @@
+// @generated by protoc-gen-es v2.10.0 with parameter "target=ts"
+// @generated from file v1/device.proto (package api.v1, syntax proto3)
+/* eslint-disable */
+
+import type { GenFile, GenMessage, GenService } from "@bufbuild/protobuf/codegenv2";
+import { fileDesc, messageDesc, serviceDesc } from "@bufbuild/protobuf/codegenv2";
+import { file_buf_validate_validate } from "../buf/validate/validate_pb";
+import { file_google_api_annotations } from "../google/api/annotations_pb";
+import type { User } from "./common_pb";
+import { file_v1_common } from "./common_pb";
+import { file_v1_options } from "./options_pb";
+import type { Message } from "@bufbuild/protobuf";
+
+/**
+ * Describes the file v1/device.proto.
+ */
+export const file_v1_device: GenFile = /*@__PURE__*/
+  fileDesc("Cg92MS9kZXZpY2UucHJvdG8SBmFwaS52MSJLChZTdGFydERldmljZUF1dGhSZXF1ZXN0EjEKC2NsaWVudF9uYW1lGAEgASgJQhy6SBlyFxhkMhNeW15ceDAwLVx4MUZceDdGXSokIqQBChdTdGFydERldmljZUF1dGhSZXNwb25zZRITCgtkZXZpY2VfY29kZRgBIAEoCRIRCgl1c2VyX2NvZGUYAiABKAkSGAoQdmVyaWZpY2F0aW9uX3VyaRgDIAEoCRIhChl2ZXJpZmljYXRpb25fdXJpX2NvbXBsZXRlGAQgASgJEhIKCmV4cGlyZXNfaW4YBSABKAUSEAoIaW50ZXJ2YWwYBiABKAUiOAoVUG9sbERldmljZUF1dGhSZXF1ZXN0Eh8KC2RldmljZV9jb2RlGAEgASgJQgq6SAdyBRABGIABIkEKFlBvbGxEZXZpY2VBdXRoUmVzcG9uc2USCwoDand0GAEgASgJEhoKBHVzZXIYAiABKAsyDC5hcGkudjEuVXNlciI3ChdMb29rdXBEZXZpY2VBdXRoUmVxdWVzdBIcCgl1c2VyX2NvZGUYASABKAlCCbpIBnIEEAEYICIvChhMb29rdXBEZXZpY2VBdXRoUmVzcG9uc2USEwoLY2xpZW50X25hbWUYASABKAkiOAoYQXBwcm92ZURldmljZUF1dGhSZXF1ZXN0EhwKCXVzZXJfY29kZRgBIAEoCUIJukgGcgQQARggIjAKGUFwcHJvdmVEZXZpY2VBdXRoUmVzcG9uc2USEwoLY2xpZW50X25hbWUYASABKAkiNQoVRGVueURldmljZUF1dGhSZXF1ZXN0EhwKCXVzZXJfY29kZRgBIAEoCUIJukgGcgQYIBABIhgKFkRlbnlEZXZpY2VBdXRoUmVzcG9uc2UyuAQKEURldmljZUF1dGhTZXJ2aWNlEmsKBVN0YXJ0Eh4uYXBpLnYxLlN0YXJ0RGV2aWNlQXV0aFJlcXVlc3QaHy5hcGkudjEuU3RhcnREZXZpY2VBdXRoUmVzcG9uc2UiIYq1GAIIAYLT5JMCFSIQL3YxL2RldmljZS9zdGFydDoBKhJnCgRQb2xsEh0uYXBpLnYxLlBvbGxEZXZpY2VBdXRoUmVxdWVzdBoeLmFwaS52MS5Qb2xsRGV2aWNlQXV0aFJlc3BvbnNlIiCKtRgCCAGC0+STAhQiDy92MS9kZXZpY2UvcG9sbDoBKhJvCgZMb29rdXASHy5hcGkudjEuTG9va3VwRGV2aWNlQXV0aFJlcXVlc3QaIC5hcGkudjEuTG9va3VwRGV2aWNlQXV0aFJlc3BvbnNlIiKKtRgCCAKC0+STAhY6ASoiES92MS9kZXZpY2UvbG9va3VwEnMKB0FwcHJvdmUSIC5hcGkudjEuQXBwcm92ZURldmljZUF1dGhSZXF1ZXN0GiEuYXBpLnYxLkFwcHJvdmVEZXZpY2VBdXRoUmVzcG9uc2UiI4q1GAIIAoLT5JMCFzoBKiISL3YxL2RldmljZS9hcHByb3ZlEmcKBERlbnkSHS5hcGkudjEuRGVueURldmljZUF1dGhSZXF1ZXN0Gh4uYXBpLnYxLkRlbnlEZXZpY2VBdXRoUmVzcG9uc2UiIIq1GAIIAoLT5JMCFCIPL3YxL2RldmljZS9kZW55OgEqQipaKGdpdGh1Yi5jb20vZGFtZWplcmFzL2dvb3NlL2FwaS9nZW4vZ28vdjFiBnByb3RvMw", [file_buf_validate_validate, file_google_api_annotations, file_v1_common, file_v1_options]);
+
+/**
+ * @generated from message api.v1.StartDeviceAuthRequest
+ */
+export type StartDeviceAuthRequest = Message<"api.v1.StartDeviceAuthRequest"> & {
+  /**
+   * Shown to the user approving the login, e.g. "goosectl on build-01"; no control characters
+   *
+   * @generated from field: string client_name = 1;
+   */
+  clientName: string;
+};
+
+/**
+ * Describes the message api.v1.StartDeviceAuthRequest.
+ * Use `create(StartDeviceAuthRequestSchema)` to create a new message.
+ */
+export const StartDeviceAuthRequestSchema: GenMessage<StartDeviceAuthRequest> = /*@__PURE__*/
+  messageDesc(file_v1_device, 0);
+
+/**
+ * @generated from message api.v1.StartDeviceAuthResponse
+ */
+export type StartDeviceAuthResponse = Message<"api.v1.StartDeviceAuthResponse"> & {
+  /**
+   * Secret the tool polls with
+   *
+   * @generated from field: string device_code = 1;
+   */
+  deviceCode: string;
+
+  /**
+   * Code the user enters at the verification URI, e.g. "WDJB-MJHT"
+   *
+   * @generated from field: string user_code = 2;
+   */
+  userCode: string;
+
+  /**
+   * @generated from field: string verification_uri = 3;
+   */
+  verificationUri: string;
+
+  /**
+   * Verification URI with the user code filled in
+   *
+   * @generated from field: string verification_uri_complete = 4;
+   */
+  verificationUriComplete: string;
+
+  /**
+   * Seconds until the codes expire
+   *
+   * @generated from field: int32 expires_in = 5;
+   */
+  expiresIn: number;
+
+  /**
+   * Minimum seconds between polls
+   *
+   * @generated from field: int32 interval = 6;
+   */
+  interval: number;
+};
+
+/**
+ * Describes the message api.v1.StartDeviceAuthResponse.
+ * Use `create(StartDeviceAuthResponseSchema)` to create a new message.
+ */
+export const StartDeviceAuthResponseSchema: GenMessage<StartDeviceAuthResponse> = /*@__PURE__*/
+  messageDesc(file_v1_device, 1);
+
+/**
+ * @generated from message api.v1.PollDeviceAuthRequest
+ */
+export type PollDeviceAuthRequest = Message<"api.v1.PollDeviceAuthRequest"> & {
+  /**
+   * @generated from field: string device_code = 1;
+   */
+  deviceCode: string;
+};
+
+/**
+ * Describes the message api.v1.PollDeviceAuthRequest.
+ * Use `create(PollDeviceAuthRequestSchema)` to create a new message.
+ */
+export const PollDeviceAuthRequestSchema: GenMessage<PollDeviceAuthRequest> = /*@__PURE__*/
+  messageDesc(file_v1_device, 2);
+
+/**
+ * @generated from message api.v1.PollDeviceAuthResponse
+ */
+export type PollDeviceAuthResponse = Message<"api.v1.PollDeviceAuthResponse"> & {
+  /**
+   * @generated from field: string jwt = 1;
+   */
+  jwt: string;
+
+  /**
+   * @generated from field: api.v1.User user = 2;
+   */
+  user?: User;
+};
+
+/**
+ * Describes the message api.v1.PollDeviceAuthResponse.
+ * Use `create(PollDeviceAuthResponseSchema)` to create a new message.
+ */
+export const PollDeviceAuthResponseSchema: GenMessage<PollDeviceAuthResponse> = /*@__PURE__*/
+  messageDesc(file_v1_device, 3);
+
+/**
+ * @generated from message api.v1.LookupDeviceAuthRequest
+ */
+export type LookupDeviceAuthRequest = Message<"api.v1.LookupDeviceAuthRequest"> & {
+  /**
+   * Case and separators are ignored
+   *
+   * @generated from field: string user_code = 1;
+   */
+  userCode: string;
+};
+
+/**
+ * Describes the message api.v1.LookupDeviceAuthRequest.
+ * Use `create(LookupDeviceAuthRequestSchema)` to create a new message.
+ */
+export const LookupDeviceAuthRequestSchema: GenMessage<LookupDeviceAuthRequest> = /*@__PURE__*/
+  messageDesc(file_v1_device, 4);
+
+/**
+ * @generated from message api.v1.LookupDeviceAuthResponse
+ */
+export type LookupDeviceAuthResponse = Message<"api.v1.LookupDeviceAuthResponse"> & {
+  /**
+   * Name the tool gave when it started the login, e.g. "goosectl on build-01"
+   *
+   * @generated from field: string client_name = 1;
+   */
+  clientName: string;
+};
+
+/**
+ * Describes the message api.v1.LookupDeviceAuthResponse.
+ * Use `create(LookupDeviceAuthResponseSchema)` to create a new message.
+ */
+export const LookupDeviceAuthResponseSchema: GenMessage<LookupDeviceAuthResponse> = /*@__PURE__*/
+  messageDesc(file_v1_device, 5);
+
+/**
+ * @generated from message api.v1.ApproveDeviceAuthRequest
+ */
+export type ApproveDeviceAuthRequest = Message<"api.v1.ApproveDeviceAuthRequest"> & {
+  /**
+   * Case and separators are ignored
+   *
+   * @generated from field: string user_code = 1;
+   */
+  userCode: string;
+};
+
+/**
+ * Describes the message api.v1.ApproveDeviceAuthRequest.
+ * Use `create(ApproveDeviceAuthRequestSchema)` to create a new message.
+ */
+export const ApproveDeviceAuthRequestSchema: GenMessage<ApproveDeviceAuthRequest> = /*@__PURE__*/
+  messageDesc(file_v1_device, 6);
+
+/**
+ * @generated from message api.v1.ApproveDeviceAuthResponse
+ */
+export type ApproveDeviceAuthResponse = Message<"api.v1.ApproveDeviceAuthResponse"> & {
+  /**
+   * @generated from field: string client_name = 1;
+   */
+  clientName: string;
+};
+
+/**
+ * Describes the message api.v1.ApproveDeviceAuthResponse.
+ * Use `create(ApproveDeviceAuthResponseSchema)` to create a new message.
+ */
+export const ApproveDeviceAuthResponseSchema: GenMessage<ApproveDeviceAuthResponse> = /*@__PURE__*/
+  messageDesc(file_v1_device, 7);
+
+/**
+ * @generated from message api.v1.DenyDeviceAuthRequest
+ */
+export type DenyDeviceAuthRequest = Message<"api.v1.DenyDeviceAuthRequest"> & {
+  /**
+   * @generated from field: string user_code = 1;
+   */
+  userCode: string;
+};
+
+/**
+ * Describes the message api.v1.DenyDeviceAuthRequest.
+ * Use `create(DenyDeviceAuthRequestSchema)` to create a new message.
+ */
+export const DenyDeviceAuthRequestSchema: GenMessage<DenyDeviceAuthRequest> = /*@__PURE__*/
+  messageDesc(file_v1_device, 8);
+
+/**
+ * @generated from message api.v1.DenyDeviceAuthResponse
+ */
+export type DenyDeviceAuthResponse = Message<"api.v1.DenyDeviceAuthResponse"> & {
+};
+
+/**
+ * Describes the message api.v1.DenyDeviceAuthResponse.
+ * Use `create(DenyDeviceAuthResponseSchema)` to create a new message.
+ */
+export const DenyDeviceAuthResponseSchema: GenMessage<DenyDeviceAuthResponse> = /*@__PURE__*/
+  messageDesc(file_v1_device, 9);
+
+/**
+ * Device authorization grant (RFC 8628) for CLIs and other tools that can't
+ * open a browser: the tool starts a login, the user approves it in the web app
+ * and the tool polls until it receives a token
+ *
+ * @generated from service api.v1.DeviceAuthService
+ */
+export const DeviceAuthService: GenService<{
+  /**
+   * Start a device login; show the user code and verification URI to the user
+   *
+   * @generated from rpc api.v1.DeviceAuthService.Start
+   */
+  start: {
+    methodKind: "unary";
+    input: typeof StartDeviceAuthRequestSchema;
+    output: typeof StartDeviceAuthResponseSchema;
+  },
+  /**
+   * Poll for the token; fails with AUTHORIZATION_PENDING until the user
+   * decides and with SLOW_DOWN when called more often than the interval
+   *
+   * @generated from rpc api.v1.DeviceAuthService.Poll
+   */
+  poll: {
+    methodKind: "unary";
+    input: typeof PollDeviceAuthRequestSchema;
+    output: typeof PollDeviceAuthResponseSchema;
+  },
+  /**
+   * Look up the client that started a device login, to show the user before
+   * they approve it. Unknown codes count toward the caller's failed attempts
+   * like those of Approve and Deny; too many fail with TOO_MANY_ATTEMPTS.
+   *
+   * @generated from rpc api.v1.DeviceAuthService.Lookup
+   */
+  lookup: {
+    methodKind: "unary";
+    input: typeof LookupDeviceAuthRequestSchema;
+    output: typeof LookupDeviceAuthResponseSchema;
+  },
+  /**
+   * Approve a device login as the current user
+   *
+   * @generated from rpc api.v1.DeviceAuthService.Approve
+   */
+  approve: {
+    methodKind: "unary";
+    input: typeof ApproveDeviceAuthRequestSchema;
+    output: typeof ApproveDeviceAuthResponseSchema;
+  },
+  /**
+   * Deny a device login
+   *
+   * @generated from rpc api.v1.DeviceAuthService.Deny
+   */
+  deny: {
+    methodKind: "unary";
+    input: typeof DenyDeviceAuthRequestSchema;
+    output: typeof DenyDeviceAuthResponseSchema;
+  },
+}> = /*@__PURE__*/
+  serviceDesc(file_v1_device, 0);
+
@@ -0,0 +1,112 @@
+// @generated by protoc-gen-es v2.10.0 with parameter "target=ts"
+// @generated from file v1/options.proto (package api.v1, syntax proto3)
+/* eslint-disable */
+
+import type { GenEnum, GenExtension, GenFile, GenMessage } from "@bufbuild/protobuf/codegenv2";
+import { enumDesc, extDesc, fileDesc, messageDesc } from "@bufbuild/protobuf/codegenv2";
+import type { MethodOptions } from "@bufbuild/protobuf/wkt";
+import { file_google_protobuf_descriptor } from "@bufbuild/protobuf/wkt";
+import type { Message } from "@bufbuild/protobuf";
+
+/**
+ * Describes the file v1/options.proto.
+ */
+export const file_v1_options: GenFile = /*@__PURE__*/
+  fileDesc("ChB2MS9vcHRpb25zLnByb3RvEgZhcGkudjEiWwoKQXV0aFBvbGljeRIgCgVsZXZlbBgBIAEoDjIRLmFwaS52MS5BdXRoTGV2ZWwSDgoGc2NvcGVzGAIgAygJEhsKBXJvbGVzGAMgAygOMgwuYXBpLnYxLlJvbGUqXAoJQXV0aExldmVsEhoKFkFVVEhfTEVWRUxfVU5TUEVDSUZJRUQQABIVChFBVVRIX0xFVkVMX1BVQkxJQxABEhwKGEFVVEhfTEVWRUxfQVVUSEVOVElDQVRFRBACKiwKBFJvbGUSFAoQUk9MRV9VTlNQRUNJRklFRBAAEg4KClJPTEVfQURNSU4QATpCCgRhdXRoEh4uZ29vZ2xlLnByb3RvYnVmLk1ldGhvZE9wdGlvbnMY0YYDIAEoCzISLmFwaS52MS5BdXRoUG9saWN5QipaKGdpdGh1Yi5jb20vZGFtZWplcmFzL2dvb3NlL2FwaS9nZW4vZ28vdjFiBnByb3RvMw", [file_google_protobuf_descriptor]);
+
+/**
+ * Access policy declared on every RPC with the (api.v1.auth) method option
+ *
+ * @generated from message api.v1.AuthPolicy
+ */
+export type AuthPolicy = Message<"api.v1.AuthPolicy"> & {
+  /**
+   * @generated from field: api.v1.AuthLevel level = 1;
+   */
+  level: AuthLevel;
+
+  /**
+   * Scopes the credential must carry; unscoped credentials are granted every scope
+   *
+   * @generated from field: repeated string scopes = 2;
+   */
+  scopes: string[];
+
+  /**
+   * Roles of which the caller must hold at least one; only valid with AUTH_LEVEL_AUTHENTICATED
+   *
+   * @generated from field: repeated api.v1.Role roles = 3;
+   */
+  roles: Role[];
+};
+
+/**
+ * Describes the message api.v1.AuthPolicy.
+ * Use `create(AuthPolicySchema)` to create a new message.
+ */
+export const AuthPolicySchema: GenMessage<AuthPolicy> = /*@__PURE__*/
+  messageDesc(file_v1_options, 0);
+
+/**
+ * Access level a procedure requires
+ *
+ * @generated from enum api.v1.AuthLevel
+ */
+export enum AuthLevel {
+  /**
+   * @generated from enum value: AUTH_LEVEL_UNSPECIFIED = 0;
+   */
+  UNSPECIFIED = 0,
+
+  /**
+   * Callable without credentials
+   *
+   * @generated from enum value: AUTH_LEVEL_PUBLIC = 1;
+   */
+  PUBLIC = 1,
+
+  /**
+   * Requires a JWT, session cookie or API key
+   *
+   * @generated from enum value: AUTH_LEVEL_AUTHENTICATED = 2;
+   */
+  AUTHENTICATED = 2,
+}
+
+/**
+ * Describes the enum api.v1.AuthLevel.
+ */
+export const AuthLevelSchema: GenEnum<AuthLevel> = /*@__PURE__*/
+  enumDesc(file_v1_options, 0);
+
+/**
+ * Role a caller may hold beyond being authenticated
+ *
+ * @generated from enum api.v1.Role
+ */
+export enum Role {
+  /**
+   * @generated from enum value: ROLE_UNSPECIFIED = 0;
+   */
+  UNSPECIFIED = 0,
+
+  /**
+   * Users listed in auth.admin_emails
+   *
+   * @generated from enum value: ROLE_ADMIN = 1;
+   */
+  ADMIN = 1,
+}
+
+/**
+ * Describes the enum api.v1.Role.
+ */
+export const RoleSchema: GenEnum<Role> = /*@__PURE__*/
+  enumDesc(file_v1_options, 1);
+
+/**
+ * @generated from extension: api.v1.AuthPolicy auth = 50001;
+ */
+export const auth: GenExtension<MethodOptions, AuthPolicy> = /*@__PURE__*/
+  extDesc(file_v1_options, 0);
+
@@ -2,15 +2,18 @@ import { createClient } from "@connectrpc/connect";
 import { createConnectTransport } from "@connectrpc/connect-web";
 import { AuthService } from "./api/v1/auth_pb";
 import { APIKeyService } from "./api/v1/apikey_pb";
+import { DeviceAuthService } from "./api/v1/device_pb";
 
 /**
  * API Client for making authenticated requests to the backend
- * Automatically includes JWT token in Authorization header
+ * Automatically includes JWT token in Authorization header, or the CSRF token
+ * when the server keeps the session in an HttpOnly cookie
  */
 class ApiClient {
   private transport;
   private authClient: ReturnType<typeof createClient<typeof AuthService>>;
   private apiKeyClient: ReturnType<typeof createClient<typeof APIKeyService>>;
+  private deviceAuthClient: ReturnType<typeof createClient<typeof DeviceAuthService>>;
 
   constructor() {
     // Create transport for Connect RPC
@@ -24,6 +27,11 @@ class ApiClient {
           if (token) {
             req.header.set("Authorization", `Bearer ${token}`);
           }
+          // In cookie session mode, echo the CSRF cookie back as a header
+          const csrfToken = this.getCSRFToken();
+          if (csrfToken) {
+            req.header.set("X-CSRF-Token", csrfToken);
+          }
           return await next(req);
         },
       ],
@@ -34,6 +42,9 @@ class ApiClient {
 
     // Create API key service client
     this.apiKeyClient = createClient(APIKeyService, this.transport);
+
+    // Create device authorization client
+    this.deviceAuthClient = createClient(DeviceAuthService, this.transport);
   }
 
   /**
@@ -43,6 +54,14 @@ class ApiClient {
     return localStorage.getItem("auth_token");
   }
 
+  /**
+   * Get the CSRF token set by the server alongside the session cookie
+   */
+  private getCSRFToken(): string | null {
+    const match = document.cookie.match(/(?:^|;\s*)goose_csrf=([^;]*)/);
+    return match ? decodeURIComponent(match[1]) : null;
+  }
+
   /**
    * Store JWT token in localStorage
    */
@@ -70,6 +89,13 @@ class ApiClient {
   get apiKey() {
     return this.apiKeyClient;
   }
+
+  /**
+   * Get the device authorization service client
+   */
+  get deviceAuth() {
+    return this.deviceAuthClient;
+  }
 }
 
 // Export singleton instance
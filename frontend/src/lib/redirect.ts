@@ -0,0 +1,10 @@
+import type { Location } from "react-router-dom";
+
+/**
+ * Where to go after logging in: the page that sent the user to /login, so
+ * links such as /device?code=... survive the detour, or the dashboard
+ */
+export function redirectTarget(state: unknown): string {
+  const from = (state as { from?: Location } | null)?.from;
+  return from ? from.pathname + from.search : "/";
+}
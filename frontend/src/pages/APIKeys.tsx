@@ -201,6 +201,7 @@ export default function APIKeys() {
                         <TableRow>
                             <TableHeader>Name</TableHeader>
                             <TableHeader>Key</TableHeader>
+                            <TableHeader>Scopes</TableHeader>
                             <TableHeader>Created</TableHeader>
                             <TableHeader>Last Used</TableHeader>
                             <TableHeader></TableHeader>
@@ -215,6 +216,17 @@ export default function APIKeys() {
                                         {key.keyMasked}
                                     </code>
                                 </TableCell>
+                                <TableCell>
+                                    {key.scopes.length > 0 ? (
+                                        <div className="flex flex-wrap gap-1">
+                                            {key.scopes.map((scope) => (
+                                                <Badge key={scope} color="blue">{scope}</Badge>
+                                            ))}
+                                        </div>
+                                    ) : (
+                                        <Badge color="zinc">Full access</Badge>
+                                    )}
+                                </TableCell>
                                 <TableCell>{formatDate(key.createdAt)}</TableCell>
                                 <TableCell>
                                     {key.lastUsedAt ? (
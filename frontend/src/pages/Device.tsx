@@ -0,0 +1,178 @@
+import {useEffect, useState} from "react";
+import {useSearchParams} from "react-router-dom";
+import {Code, ConnectError} from "@connectrpc/connect";
+import {apiClient} from "@/lib/apiClient";
+import {Heading} from "@/components/heading";
+import {Button} from "@/components/button";
+import {Field, Label, Description} from "@/components/fieldset";
+import {Input} from "@/components/input";
+import {Text} from "@/components/text";
+
+type Result = { approved: true; clientName: string } | { approved: false };
+
+export default function Device() {
+    const [searchParams] = useSearchParams();
+    const [userCode, setUserCode] = useState(searchParams.get("code") ?? "");
+    const [isSubmitting, setIsSubmitting] = useState(false);
+    const [error, setError] = useState<string | null>(null);
+    const [result, setResult] = useState<Result | null>(null);
+    // The client that started the login, shown before the user approves it
+    const [clientName, setClientName] = useState<string | null>(null);
+
+    const describeError = (err: unknown) => {
+        const connectErr = ConnectError.from(err);
+        if (connectErr.code === Code.NotFound) {
+            return "This code is unknown or has expired. Start the login again on your device.";
+        }
+        if (connectErr.code === Code.ResourceExhausted) {
+            return "Too many incorrect codes. Wait a few minutes before trying again.";
+        }
+        return "Something went wrong. Please try again.";
+    };
+
+    const lookup = async (code: string) => {
+        if (!code.trim()) {
+            return;
+        }
+
+        try {
+            setIsSubmitting(true);
+            setError(null);
+            const response = await apiClient.deviceAuth.lookup({userCode: code.trim()});
+            setClientName(response.clientName);
+        } catch (err) {
+            console.error("Failed to look up device login:", err);
+            setError(describeError(err));
+        } finally {
+            setIsSubmitting(false);
+        }
+    };
+
+    // A code from the verification link is looked up right away; approving still takes a click
+    useEffect(() => {
+        const code = searchParams.get("code");
+        if (code) {
+            lookup(code);
+        }
+        // eslint-disable-next-line react-hooks/exhaustive-deps
+    }, []);
+
+    const handleApprove = async () => {
+        if (!userCode.trim()) {
+            return;
+        }
+
+        try {
+            setIsSubmitting(true);
+            setError(null);
+            const response = await apiClient.deviceAuth.approve({userCode: userCode.trim()});
+            setResult({approved: true, clientName: response.clientName});
+        } catch (err) {
+            console.error("Failed to approve device login:", err);
+            setError(describeError(err));
+        } finally {
+            setIsSubmitting(false);
+        }
+    };
+
+    const handleDeny = async () => {
+        if (!userCode.trim()) {
+            return;
+        }
+
+        try {
+            setIsSubmitting(true);
+            setError(null);
+            await apiClient.deviceAuth.deny({userCode: userCode.trim()});
+            setResult({approved: false});
+        } catch (err) {
+            console.error("Failed to deny device login:", err);
+            setError(describeError(err));
+        } finally {
+            setIsSubmitting(false);
+        }
+    };
+
+    return (
+        <div className="max-w-xl mx-auto px-4 sm:px-6 lg:px-8 py-10">
+            <Heading>Device Login</Heading>
+            <Text className="mt-2">
+                A command-line tool or another device is asking to sign in to your account.
+            </Text>
+
+            {error && (
+                <div className="mt-6 rounded-lg bg-red-50 p-4 text-sm text-red-800 dark:bg-red-950 dark:text-red-200">
+                    {error}
+                </div>
+            )}
+
+            {result ? (
+                <div className="mt-8 rounded-lg border border-zinc-200 p-6 dark:border-zinc-800">
+                    {result.approved ? (
+                        <Text>
+                            {result.clientName ? <strong>{result.clientName}</strong> : "The device"} is now signed
+                            in. You can close this page and return to your device.
+                        </Text>
+                    ) : (
+                        <Text>The login was denied. The device has not been signed in.</Text>
+                    )}
+                </div>
+            ) : clientName === null ? (
+                <form
+                    className="mt-8 space-y-6"
+                    onSubmit={(e) => {
+                        e.preventDefault();
+                        lookup(userCode);
+                    }}
+                >
+                    <Field>
+                        <Label>Code</Label>
+                        <Description>Enter the code shown on your device.</Description>
+                        <Input
+                            value={userCode}
+                            onChange={(e) => setUserCode(e.target.value)}
+                            placeholder="XXXX-XXXX"
+                            autoComplete="off"
+                            autoFocus
+                            className="font-mono uppercase"
+                        />
+                    </Field>
+                    <Button type="submit" color="dark/zinc" disabled={isSubmitting || !userCode.trim()}>
+                        Continue
+                    </Button>
+                </form>
+            ) : (
+                <div className="mt-8 space-y-6">
+                    <div className="rounded-lg border border-zinc-200 p-6 dark:border-zinc-800">
+                        <Text>
+                            {clientName ? <strong>{clientName}</strong> : "A device"} wants to sign in to your account
+                            with the code <span className="font-mono uppercase">{userCode.trim()}</span>.
+                        </Text>
+                        <Text className="mt-2">
+                            Only approve if you started the login yourself and the code matches the one on your
+                            device.
+                        </Text>
+                    </div>
+                    <div className="flex gap-4">
+                        <Button color="dark/zinc" onClick={handleApprove} disabled={isSubmitting}>
+                            Approve
+                        </Button>
+                        <Button outline onClick={handleDeny} disabled={isSubmitting}>
+                            Deny
+                        </Button>
+                        <Button
+                            plain
+                            onClick={() => {
+                                setClientName(null);
+                                setError(null);
+                            }}
+                            disabled={isSubmitting}
+                        >
+                            Use a different code
+                        </Button>
+                    </div>
+                </div>
+            )}
+        </div>
+    );
+}
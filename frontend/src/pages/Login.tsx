@@ -1,10 +1,11 @@
 import { useEffect, useState } from "react";
-import { useNavigate } from "react-router-dom";
+import { useLocation, useNavigate } from "react-router-dom";
 import { useAuth } from "@/contexts/AuthContext";
 import { AuthLayout } from "@/components/auth-layout";
 import { Button } from "@/components/button";
 import { Heading } from "@/components/heading";
 import { Logo } from "@/logo";
+import { redirectTarget } from "@/lib/redirect";
 import {
   initGoogleSignIn,
   initializeOAuth2Client,
@@ -13,6 +14,7 @@ import {
 
 export default function Login() {
   const navigate = useNavigate();
+  const location = useLocation();
   const { loginWithGoogle } = useAuth();
   const [isLoading, setIsLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
@@ -26,7 +28,7 @@ export default function Login() {
 
       try {
         await loginWithGoogle(idToken);
-        navigate("/");
+        navigate(redirectTarget(location.state), { replace: true });
       } catch (err) {
         console.error("Login error:", err);
         setError("Login failed. Please try again.");
@@ -45,7 +47,7 @@ export default function Login() {
         console.error("Failed to initialize Google Sign-In:", err);
         setError("Failed to load Google Sign-In. Please refresh the page.");
       });
-  }, [loginWithGoogle, navigate]);
+  }, [loginWithGoogle, navigate, location.state]);
 
   const handleCustomButtonClick = () => {
     if (!isGoogleReady) {
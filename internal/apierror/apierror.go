@@ -0,0 +1,206 @@
+// Package apierror is the error taxonomy shared by the RPC handlers. An Error
+// pairs a Connect code and a stable reason with an error that is safe to show
+// to clients; internal causes are logged by Interceptor and never sent.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Domain is the google.rpc.ErrorInfo domain of every error returned by the API
+const Domain = "goose"
+
+// Reasons shared across services; services define their own for domain errors
+const (
+	ReasonInternal        = "INTERNAL"
+	ReasonInvalidArgument = "INVALID_ARGUMENT"
+	ReasonNotFound        = "NOT_FOUND"
+	ReasonUnavailable     = "UNAVAILABLE"
+)
+
+var ErrInternal = errors.New("internal error")
+
+// Error is a domain error returned by handlers and interceptors
+type Error struct {
+	code connect.Code
+	// reason is the google.rpc.ErrorInfo reason, UPPER_SNAKE_CASE
+	reason string
+	// err is sent to the client
+	err error
+	// cause is logged but redacted from the response
+	cause      error
+	metadata   map[string]string
+	violations []*errdetails.BadRequest_FieldViolation
+	retryDelay time.Duration
+	details    []proto.Message
+}
+
+// New creates an error whose message, err.Error(), is shown to clients.
+// err is usually a sentinel, so callers can still match it with errors.Is.
+func New(code connect.Code, reason string, err error) *Error {
+	return &Error{code: code, reason: reason, err: err}
+}
+
+func InvalidArgument(reason string, err error) *Error {
+	return New(connect.CodeInvalidArgument, reason, err)
+}
+
+func NotFound(reason string, err error) *Error {
+	return New(connect.CodeNotFound, reason, err)
+}
+
+func Unauthenticated(reason string, err error) *Error {
+	return New(connect.CodeUnauthenticated, reason, err)
+}
+
+func PermissionDenied(reason string, err error) *Error {
+	return New(connect.CodePermissionDenied, reason, err)
+}
+
+func FailedPrecondition(reason string, err error) *Error {
+	return New(connect.CodeFailedPrecondition, reason, err)
+}
+
+func ResourceExhausted(reason string, err error) *Error {
+	return New(connect.CodeResourceExhausted, reason, err)
+}
+
+// Unavailable tells clients to retry after delay
+func Unavailable(delay time.Duration, cause error) *Error {
+	return New(connect.CodeUnavailable, ReasonUnavailable, errors.New("service unavailable")).
+		WithCause(cause).
+		WithRetryDelay(delay)
+}
+
+// Internal reports a server-side failure. Clients see message, e.g. "failed to
+// create user"; cause is logged with the request ID.
+func Internal(message string, cause error) *Error {
+	return New(connect.CodeInternal, ReasonInternal, errors.New(message)).WithCause(cause)
+}
+
+// WithCause attaches an error that is logged but not sent to the client
+func (e *Error) WithCause(cause error) *Error {
+	e.cause = cause
+	return e
+}
+
+// WithMetadata adds a google.rpc.ErrorInfo metadata entry
+func (e *Error) WithMetadata(key, value string) *Error {
+	if e.metadata == nil {
+		e.metadata = make(map[string]string)
+	}
+	e.metadata[key] = value
+	return e
+}
+
+// WithFieldViolation adds a google.rpc.BadRequest field violation
+func (e *Error) WithFieldViolation(field, description string) *Error {
+	e.violations = append(e.violations, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: description,
+	})
+	return e
+}
+
+// WithRetryDelay adds a google.rpc.RetryInfo detail
+func (e *Error) WithRetryDelay(delay time.Duration) *Error {
+	e.retryDelay = delay
+	return e
+}
+
+// WithDetail adds any other error detail
+func (e *Error) WithDetail(detail proto.Message) *Error {
+	e.details = append(e.details, detail)
+	return e
+}
+
+// Code returns the Connect code sent to clients
+func (e *Error) Code() connect.Code {
+	return e.code
+}
+
+// Reason returns the google.rpc.ErrorInfo reason
+func (e *Error) Reason() string {
+	return e.reason
+}
+
+// Cause returns the internal cause, if any
+func (e *Error) Cause() error {
+	return e.cause
+}
+
+// Error includes the cause; use Connect to get what clients see
+func (e *Error) Error() string {
+	if e.cause == nil {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.err, e.cause)
+}
+
+// Unwrap lets errors.Is and errors.As match both the public error and the cause
+func (e *Error) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.err}
+	}
+	return []error{e.err, e.cause}
+}
+
+// Connect converts e to the error sent to clients: the public error and its
+// details, without the cause. requestID is added to the ErrorInfo metadata.
+func (e *Error) Connect(requestID string) *connect.Error {
+	connectErr := connect.NewError(e.code, e.err)
+
+	metadata := make(map[string]string, len(e.metadata)+1)
+	for key, value := range e.metadata {
+		metadata[key] = value
+	}
+	if requestID != "" {
+		metadata["request_id"] = requestID
+	}
+	details := []proto.Message{&errdetails.ErrorInfo{
+		Reason:   e.reason,
+		Domain:   Domain,
+		Metadata: metadata,
+	}}
+	if len(e.violations) > 0 {
+		details = append(details, &errdetails.BadRequest{FieldViolations: e.violations})
+	}
+	if e.retryDelay > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.retryDelay)})
+	}
+	details = append(details, e.details...)
+
+	for _, detail := range details {
+		if d, err := connect.NewErrorDetail(detail); err == nil {
+			connectErr.AddDetail(d)
+		}
+	}
+	if e.retryDelay > 0 {
+		// plain HTTP clients understand Retry-After, not RetryInfo
+		connectErr.Meta().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.retryDelay.Seconds()))))
+	}
+	return connectErr
+}
+
+// From returns err as an *Error. Errors that are neither an *Error nor a
+// *connect.Error are internal: their text never reaches the client.
+func From(err error) (*Error, bool) {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		return nil, false
+	}
+	return Internal(ErrInternal.Error(), err), true
+}
@@ -0,0 +1,194 @@
+package apierror_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/damejeras/goose/internal/apierror"
+	"github.com/damejeras/goose/internal/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var errKeyNotFound = errors.New("API key not found")
+
+// details decodes the error details of err by type
+func details(t *testing.T, err *connect.Error) map[string]proto.Message {
+	t.Helper()
+
+	decoded := make(map[string]proto.Message)
+	for _, detail := range err.Details() {
+		msg, err := detail.Value()
+		if err != nil {
+			t.Fatalf("decode %s: %v", detail.Type(), err)
+		}
+		decoded[detail.Type()] = msg
+	}
+	return decoded
+}
+
+func TestConnect(t *testing.T) {
+	cause := errors.New("sqlite: database is locked")
+	err := apierror.InvalidArgument("INVALID_NAME", errors.New("invalid API key name")).
+		WithCause(cause).
+		WithMetadata("max_length", "64").
+		WithFieldViolation("name", "must be at most 64 characters").
+		WithRetryDelay(1500 * time.Millisecond).
+		WithDetail(&errdetails.Help{Links: []*errdetails.Help_Link{{Url: "https://example.com/docs"}}})
+
+	connectErr := err.Connect("req-123")
+	if connectErr.Code() != connect.CodeInvalidArgument || connectErr.Message() != "invalid API key name" {
+		t.Errorf("error = %v, want invalid_argument with the public message only", connectErr)
+	}
+	if strings.Contains(connectErr.Error(), "sqlite") {
+		t.Errorf("error exposes the cause: %v", connectErr)
+	}
+	if got := connectErr.Meta().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2, the delay rounded up", got)
+	}
+
+	decoded := details(t, connectErr)
+	if len(decoded) != 4 {
+		t.Errorf("details = %v, want ErrorInfo, BadRequest, RetryInfo and Help", decoded)
+	}
+	info, _ := decoded["google.rpc.ErrorInfo"].(*errdetails.ErrorInfo)
+	if info.GetReason() != "INVALID_NAME" || info.GetDomain() != apierror.Domain ||
+		info.GetMetadata()["max_length"] != "64" || info.GetMetadata()["request_id"] != "req-123" {
+		t.Errorf("ErrorInfo = %v", info)
+	}
+	badRequest, _ := decoded["google.rpc.BadRequest"].(*errdetails.BadRequest)
+	if violations := badRequest.GetFieldViolations(); len(violations) != 1 || violations[0].GetField() != "name" {
+		t.Errorf("BadRequest = %v, want the name violation", badRequest)
+	}
+	retry, _ := decoded["google.rpc.RetryInfo"].(*errdetails.RetryInfo)
+	if got := retry.GetRetryDelay().AsDuration(); got != 1500*time.Millisecond {
+		t.Errorf("RetryInfo delay = %s, want 1.5s", got)
+	}
+	if help, _ := decoded["google.rpc.Help"].(*errdetails.Help); len(help.GetLinks()) != 1 {
+		t.Errorf("Help = %v", help)
+	}
+}
+
+func TestConnectMinimal(t *testing.T) {
+	connectErr := apierror.NotFound("API_KEY_NOT_FOUND", errKeyNotFound).Connect("")
+
+	decoded := details(t, connectErr)
+	info, ok := decoded["google.rpc.ErrorInfo"].(*errdetails.ErrorInfo)
+	if len(decoded) != 1 || !ok {
+		t.Fatalf("details = %v, want only ErrorInfo", decoded)
+	}
+	if len(info.GetMetadata()) != 0 {
+		t.Errorf("ErrorInfo metadata = %v, want none without a request ID", info.GetMetadata())
+	}
+	if got := connectErr.Meta().Get("Retry-After"); got != "" {
+		t.Errorf("Retry-After = %q without a retry delay", got)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	err := apierror.Unavailable(30*time.Second, errors.New("migrations running"))
+	connectErr := err.Connect("")
+
+	if connectErr.Code() != connect.CodeUnavailable || err.Reason() != apierror.ReasonUnavailable {
+		t.Errorf("error = %v, reason %s", connectErr, err.Reason())
+	}
+	if got := connectErr.Meta().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+}
+
+func TestErrorMatching(t *testing.T) {
+	cause := errors.New("sqlite: database is locked")
+	err := fmt.Errorf("delete key: %w", apierror.NotFound("API_KEY_NOT_FOUND", errKeyNotFound).WithCause(cause))
+
+	if !errors.Is(err, errKeyNotFound) || !errors.Is(err, cause) {
+		t.Error("errors.Is doesn't match the public error and the cause")
+	}
+	if got := err.Error(); got != "delete key: API key not found: sqlite: database is locked" {
+		t.Errorf("Error() = %q, want the cause included", got)
+	}
+}
+
+func TestFrom(t *testing.T) {
+	apiErr := apierror.PermissionDenied("ADMIN_ONLY", errors.New("forbidden"))
+	if got, ok := apierror.From(fmt.Errorf("wrapped: %w", apiErr)); !ok || got != apiErr {
+		t.Errorf("From(wrapped *Error) = %v, %t; want the *Error", got, ok)
+	}
+
+	if got, ok := apierror.From(connect.NewError(connect.CodeCanceled, context.Canceled)); ok {
+		t.Errorf("From(*connect.Error) = %v, want it left as is", got)
+	}
+
+	cause := errors.New("disk full")
+	got, ok := apierror.From(cause)
+	if !ok || got.Code() != connect.CodeInternal || got.Reason() != apierror.ReasonInternal || got.Cause() != cause {
+		t.Fatalf("From(plain error) = %v, %t; want an internal error caused by it", got, ok)
+	}
+	if connectErr := got.Connect(""); connectErr.Message() != apierror.ErrInternal.Error() {
+		t.Errorf("message = %q, want %q", connectErr.Message(), apierror.ErrInternal)
+	}
+}
+
+// TestInterceptor checks the details clients receive and that causes are logged, not sent
+func TestInterceptor(t *testing.T) {
+	const procedure = "/test.v1.TestService/Call"
+
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   connect.Code
+		wantReason string
+		wantLogged string
+	}{
+		{name: "domain error", err: apierror.NotFound("API_KEY_NOT_FOUND", errKeyNotFound), wantCode: connect.CodeNotFound, wantReason: "API_KEY_NOT_FOUND"},
+		{name: "cause", err: apierror.Internal("failed to delete API key", errors.New("database is locked")), wantCode: connect.CodeInternal, wantReason: apierror.ReasonInternal, wantLogged: "database is locked"},
+		{name: "plain error", err: errors.New("disk full"), wantCode: connect.CodeInternal, wantReason: apierror.ReasonInternal, wantLogged: "disk full"},
+		{name: "connect error", err: connect.NewError(connect.CodeAborted, errors.New("conflict")), wantCode: connect.CodeAborted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			call := func(ctx context.Context, req *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+				return nil, tt.err
+			}
+			mux := http.NewServeMux()
+			mux.Handle(procedure, connect.NewUnaryHandler(procedure, call, connect.WithInterceptors(apierror.NewInterceptor(logger))))
+			server := httptest.NewServer(logging.Middleware(logger)(mux))
+			t.Cleanup(server.Close)
+
+			client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+procedure)
+			_, err := client.CallUnary(t.Context(), connect.NewRequest(&emptypb.Empty{}))
+			var connectErr *connect.Error
+			if !errors.As(err, &connectErr) || connectErr.Code() != tt.wantCode {
+				t.Fatalf("error = %v, want %s", err, tt.wantCode)
+			}
+
+			info, _ := details(t, connectErr)["google.rpc.ErrorInfo"].(*errdetails.ErrorInfo)
+			if info.GetReason() != tt.wantReason {
+				t.Errorf("reason = %q, want %q", info.GetReason(), tt.wantReason)
+			}
+			if tt.wantReason != "" && info.GetMetadata()["request_id"] != connectErr.Meta().Get(logging.RequestIDHeader) {
+				t.Errorf("ErrorInfo request_id = %q, response header has %q", info.GetMetadata()["request_id"], connectErr.Meta().Get(logging.RequestIDHeader))
+			}
+			if tt.wantLogged != "" {
+				if strings.Contains(connectErr.Message(), tt.wantLogged) {
+					t.Errorf("message %q exposes the cause", connectErr.Message())
+				}
+				if !strings.Contains(logs.String(), tt.wantLogged) || !strings.Contains(logs.String(), "level=ERROR") {
+					t.Errorf("cause not logged at error level: %s", logs.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package apierror
+
+import (
+	"context"
+	"log/slog"
+
+	"connectrpc.com/connect"
+	"github.com/damejeras/goose/internal/logging"
+)
+
+// Interceptor converts errors returned further down the chain to Connect
+// errors with details. Causes are logged here, once, with the request-scoped
+// logger and are redacted from the response.
+type Interceptor struct {
+	logger *slog.Logger
+}
+
+// NewInterceptor creates an error interceptor; logger is used outside a request scope
+func NewInterceptor(logger *slog.Logger) *Interceptor {
+	return &Interceptor{logger: logger}
+}
+
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		res, err := next(ctx, req)
+		if err != nil && !req.Spec().IsClient {
+			return nil, i.convert(ctx, err)
+		}
+		return res, err
+	}
+}
+
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := next(ctx, conn); err != nil {
+			return i.convert(ctx, err)
+		}
+		return nil
+	}
+}
+
+func (i *Interceptor) convert(ctx context.Context, err error) error {
+	apiErr, ok := From(err)
+	if !ok {
+		return err
+	}
+
+	if cause := apiErr.Cause(); cause != nil {
+		level := slog.LevelWarn
+		if apiErr.Code() == connect.CodeInternal || apiErr.Code() == connect.CodeUnknown {
+			level = slog.LevelError
+		}
+		logging.FromContext(ctx, i.logger).Log(ctx, level, apiErr.err.Error(), "reason", apiErr.Reason(), "error", cause)
+	}
+
+	requestID, _ := logging.RequestID(ctx)
+	return apiErr.Connect(requestID)
+}
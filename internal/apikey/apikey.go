@@ -3,6 +3,7 @@ package apikey
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/base64"
 	"fmt"
 	"strings"
@@ -64,3 +65,19 @@ func Validate(key string) bool {
 	// Should be base64 encoded, roughly 43 characters for 32 bytes
 	return len(withoutPrefix) >= 40
 }
+
+// encodeScopes stores scopes space-separated; keys with full access store null
+func encodeScopes(scopes []string) sql.NullString {
+	if len(scopes) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: strings.Join(scopes, " "), Valid: true}
+}
+
+// decodeScopes returns the scopes a key is limited to, or nil for full access
+func decodeScopes(scopes sql.NullString) []string {
+	if !scopes.Valid {
+		return nil
+	}
+	return strings.Fields(scopes.String)
+}
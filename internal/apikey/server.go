@@ -3,27 +3,45 @@ package apikey
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 
 	"connectrpc.com/connect"
 	v1 "github.com/damejeras/goose/api/gen/go/v1"
 	"github.com/damejeras/goose/db/sqlc"
+	"github.com/damejeras/goose/internal/apierror"
 	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/logging"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+var (
+	ErrNotFound     = errors.New("API key not found")
+	ErrUnknownScope = errors.New("unknown scope")
+)
+
+// Reasons reported in the google.rpc.ErrorInfo of API key errors
+const (
+	ReasonAPIKeyNotFound = "API_KEY_NOT_FOUND"
+	ReasonUnknownScope   = "UNKNOWN_SCOPE"
+)
+
 // Server implements the APIKeyService
 type Server struct {
 	queries *sqlc.Queries
 	logger  *slog.Logger
+	scopes  []string
 }
 
-// NewServer creates a new API key server
-func NewServer(queries *sqlc.Queries, logger *slog.Logger) *Server {
+// NewServer creates a new API key server.
+// scopes lists the scopes keys can be limited to, see auth.MethodPolicies.Scopes.
+func NewServer(queries *sqlc.Queries, logger *slog.Logger, scopes []string) *Server {
 	return &Server{
 		queries: queries,
 		logger:  logger,
+		scopes:  scopes,
 	}
 }
 
@@ -32,18 +50,18 @@ func (s *Server) CreateAPIKey(ctx context.Context, req *connect.Request[v1.Creat
 	// Get user ID from context (set by auth middleware)
 	userID, ok := auth.GetUserIDFromContext(ctx)
 	if !ok {
-		return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("user not authenticated"))
+		return nil, apierror.Unauthenticated(auth.ReasonMissingToken, auth.ErrUnauthorized)
 	}
 
-	if req.Msg.Name == "" {
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("name is required"))
+	scopes, err := s.checkScopes(ctx, req.Msg.Scopes)
+	if err != nil {
+		return nil, err
 	}
 
 	// generateKey new API key
 	id, key, err := generateKey()
 	if err != nil {
-		s.logger.Error("failed to generate API key", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to generate API key"))
+		return nil, apierror.Internal("failed to generate API key", err)
 	}
 
 	// hash the key for storage
@@ -60,33 +78,64 @@ func (s *Server) CreateAPIKey(ctx context.Context, req *connect.Request[v1.Creat
 		KeyHash:   keyHash,
 		KeyPrefix: prefix,
 		KeySuffix: suffix,
+		Scopes:    encodeScopes(scopes),
 	})
 	if err != nil {
-		s.logger.Error("failed to create API key", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to create API key"))
+		return nil, apierror.Internal("failed to create API key", err)
 	}
+	s.log(ctx).InfoContext(ctx, "API key created", "user_id", userID, "api_key_id", dbKey.ID, "scopes", scopes)
 
 	return connect.NewResponse(&v1.CreateAPIKeyResponse{
 		Id:        dbKey.ID,
 		Name:      dbKey.Name,
 		Key:       key, // Return the full key - only time it's shown
 		CreatedAt: timestamppb.New(dbKey.CreatedAt),
+		Scopes:    decodeScopes(dbKey.Scopes),
 	}), nil
 }
 
+// checkScopes returns the requested scopes sorted and without duplicates. Each
+// must be known, and callers with a scoped credential may only create keys
+// limited to scopes they have themselves.
+func (s *Server) checkScopes(ctx context.Context, requested []string) ([]string, error) {
+	scopes := slices.Clone(requested)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	for _, scope := range scopes {
+		if !slices.Contains(s.scopes, scope) {
+			return nil, apierror.InvalidArgument(ReasonUnknownScope, fmt.Errorf("%w: %q", ErrUnknownScope, scope)).
+				WithFieldViolation("scopes", fmt.Sprintf("unknown scope %q", scope))
+		}
+	}
+
+	callerScopes := auth.GetScopesFromContext(ctx)
+	if callerScopes == nil {
+		return scopes, nil
+	}
+	if len(scopes) == 0 {
+		return nil, apierror.PermissionDenied(auth.ReasonInsufficientScopes, auth.ErrInsufficientScopes)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(callerScopes, scope) {
+			return nil, apierror.PermissionDenied(auth.ReasonInsufficientScopes, auth.ErrInsufficientScopes)
+		}
+	}
+	return scopes, nil
+}
+
 // ListAPIKeys returns all API keys for the authenticated user
 func (s *Server) ListAPIKeys(ctx context.Context, req *connect.Request[v1.ListAPIKeysRequest]) (*connect.Response[v1.ListAPIKeysResponse], error) {
 	// Get user ID from context
 	userID, ok := auth.GetUserIDFromContext(ctx)
 	if !ok {
-		return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("user not authenticated"))
+		return nil, apierror.Unauthenticated(auth.ReasonMissingToken, auth.ErrUnauthorized)
 	}
 
 	// Get API keys from database
 	dbKeys, err := s.queries.ListAPIKeysByUserID(ctx, userID)
 	if err != nil {
-		s.logger.Error("failed to list API keys", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to list API keys"))
+		return nil, apierror.Internal("failed to list API keys", err)
 	}
 
 	// Convert to proto messages with masked keys
@@ -106,6 +155,7 @@ func (s *Server) ListAPIKeys(ctx context.Context, req *connect.Request[v1.ListAP
 			KeyMasked:  maskedKey,
 			CreatedAt:  timestamppb.New(dbKey.CreatedAt),
 			LastUsedAt: lastUsedAt,
+			Scopes:     decodeScopes(dbKey.Scopes),
 		}
 	}
 
@@ -119,11 +169,7 @@ func (s *Server) DeleteAPIKey(ctx context.Context, req *connect.Request[v1.Delet
 	// Get user ID from context
 	userID, ok := auth.GetUserIDFromContext(ctx)
 	if !ok {
-		return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("user not authenticated"))
-	}
-
-	if req.Msg.Id == "" {
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("id is required"))
+		return nil, apierror.Unauthenticated(auth.ReasonMissingToken, auth.ErrUnauthorized)
 	}
 
 	// Delete the API key
@@ -133,11 +179,11 @@ func (s *Server) DeleteAPIKey(ctx context.Context, req *connect.Request[v1.Delet
 	})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("API key not found"))
+			return nil, apierror.NotFound(ReasonAPIKeyNotFound, ErrNotFound)
 		}
-		s.logger.Error("failed to delete API key", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to delete API key"))
+		return nil, apierror.Internal("failed to delete API key", err)
 	}
+	s.log(ctx).InfoContext(ctx, "API key deleted", "user_id", userID, "api_key_id", req.Msg.Id)
 
 	return connect.NewResponse(&v1.DeleteAPIKeyResponse{
 		Success: true,
@@ -149,15 +195,7 @@ func (s *Server) UpdateAPIKey(ctx context.Context, req *connect.Request[v1.Updat
 	// Get user ID from context
 	userID, ok := auth.GetUserIDFromContext(ctx)
 	if !ok {
-		return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("user not authenticated"))
-	}
-
-	if req.Msg.Id == "" {
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("id is required"))
-	}
-
-	if req.Msg.Name == "" {
-		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("name is required"))
+		return nil, apierror.Unauthenticated(auth.ReasonMissingToken, auth.ErrUnauthorized)
 	}
 
 	// Update the API key
@@ -168,11 +206,11 @@ func (s *Server) UpdateAPIKey(ctx context.Context, req *connect.Request[v1.Updat
 	})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("API key not found"))
+			return nil, apierror.NotFound(ReasonAPIKeyNotFound, ErrNotFound)
 		}
-		s.logger.Error("failed to update API key", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to update API key"))
+		return nil, apierror.Internal("failed to update API key", err)
 	}
+	s.log(ctx).InfoContext(ctx, "API key renamed", "user_id", userID, "api_key_id", dbKey.ID)
 
 	// Reconstruct the masked key
 	maskedKey := fmt.Sprintf("%s****...****%s", dbKey.KeyPrefix, dbKey.KeySuffix)
@@ -189,6 +227,12 @@ func (s *Server) UpdateAPIKey(ctx context.Context, req *connect.Request[v1.Updat
 			KeyMasked:  maskedKey,
 			CreatedAt:  timestamppb.New(dbKey.CreatedAt),
 			LastUsedAt: lastUsedAt,
+			Scopes:     decodeScopes(dbKey.Scopes),
 		},
 	}), nil
 }
+
+// log returns the request-scoped logger
+func (s *Server) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
@@ -0,0 +1,72 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/damejeras/goose/db/sqlc"
+	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/logging"
+)
+
+// UsageRecorder is notified of every request authenticated with an API key
+type UsageRecorder interface {
+	// APIKeyUsed reports whether the key was limited to scopes
+	APIKeyUsed(scoped bool)
+}
+
+// Validator authenticates API keys for the auth interceptor
+type Validator struct {
+	queries *sqlc.Queries
+	logger  *slog.Logger
+	usage   UsageRecorder
+}
+
+// NewValidator creates a new API key validator.
+// usage may be nil when key usage isn't recorded.
+func NewValidator(queries *sqlc.Queries, logger *slog.Logger, usage UsageRecorder) *Validator {
+	return &Validator{
+		queries: queries,
+		logger:  logger,
+		usage:   usage,
+	}
+}
+
+// IsAPIKey reports whether token has the API key format
+func (v *Validator) IsAPIKey(token string) bool {
+	return Validate(token)
+}
+
+// ValidateAPIKey looks up the key by its hash, loads its owner and records its
+// use. The identity carries the scopes the key is limited to.
+func (v *Validator) ValidateAPIKey(ctx context.Context, key string) (auth.Identity, error) {
+	dbKey, err := v.queries.GetAPIKeyByHash(ctx, hash(key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return auth.Identity{}, auth.ErrInvalidToken
+		}
+		return auth.Identity{}, fmt.Errorf("look up API key: %w", err)
+	}
+
+	user, err := v.queries.GetUser(ctx, dbKey.UserID)
+	if err != nil {
+		return auth.Identity{}, fmt.Errorf("look up owner of API key %s: %w", dbKey.ID, err)
+	}
+
+	if err := v.queries.UpdateAPIKeyLastUsed(ctx, dbKey.ID); err != nil {
+		v.log(ctx).WarnContext(ctx, "failed to update API key last used", "api_key_id", dbKey.ID, "error", err)
+	}
+	scopes := decodeScopes(dbKey.Scopes)
+	if v.usage != nil {
+		v.usage.APIKeyUsed(scopes != nil)
+	}
+
+	return auth.Identity{UserID: user.ID, Email: user.Email, Scopes: scopes}, nil
+}
+
+// log returns the request-scoped logger
+func (v *Validator) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, v.logger)
+}
@@ -16,12 +16,54 @@ var (
 	ErrTokenExpired = errors.New("token expired")
 	ErrUnauthorized = errors.New("unauthorized")
 	ErrMissingToken = errors.New("missing token")
+
+	ErrEmailNotVerified = errors.New("email address is not verified")
+	ErrUserNotFound     = errors.New("user not found")
+)
+
+// Reasons reported in the google.rpc.ErrorInfo of auth errors
+const (
+	ReasonMissingToken       = "MISSING_TOKEN"
+	ReasonInvalidToken       = "INVALID_TOKEN"
+	ReasonTokenExpired       = "TOKEN_EXPIRED"
+	ReasonInvalidCSRFToken   = "INVALID_CSRF_TOKEN"
+	ReasonNoMethodPolicy     = "NO_METHOD_POLICY"
+	ReasonInsufficientScopes = "INSUFFICIENT_SCOPES"
+	ReasonRoleRequired       = "ROLE_REQUIRED"
+	ReasonEmailNotVerified   = "EMAIL_NOT_VERIFIED"
+	ReasonSignupRejected     = "SIGNUP_REJECTED"
+	ReasonUserNotFound       = "USER_NOT_FOUND"
+
+	ReasonUnknownServicePrincipal = "UNKNOWN_SERVICE_PRINCIPAL"
 )
 
 type Config struct {
 	GoogleClientID string
 	JWTSecret      []byte
 	JWTExpiration  time.Duration
+	Signup         SignupPolicy
+	Cookies        CookieConfig
+	// AdminEmails lists the users holding ROLE_ADMIN
+	AdminEmails []string
+	// GoogleTokenVerifier checks Google ID tokens; defaults to verifying against Google's public certs
+	GoogleTokenVerifier GoogleTokenVerifier
+}
+
+// GoogleTokenVerifier verifies the signature, expiry and audience of a Google ID token
+// and returns its claims
+type GoogleTokenVerifier interface {
+	Verify(ctx context.Context, idToken, audience string) (map[string]interface{}, error)
+}
+
+// googleCertsVerifier verifies ID tokens against Google's published certificates
+type googleCertsVerifier struct{}
+
+func (googleCertsVerifier) Verify(ctx context.Context, idToken, audience string) (map[string]interface{}, error) {
+	payload, err := idtoken.Validate(ctx, idToken, audience)
+	if err != nil {
+		return nil, err
+	}
+	return payload.Claims, nil
 }
 
 type Service struct {
@@ -32,6 +74,12 @@ func NewService(config Config) *Service {
 	if config.JWTExpiration == 0 {
 		config.JWTExpiration = 24 * time.Hour // default 24 hours
 	}
+	if config.GoogleTokenVerifier == nil {
+		config.GoogleTokenVerifier = googleCertsVerifier{}
+	}
+	if config.Signup.Mode == "" {
+		config.Signup.Mode = SignupOpen
+	}
 	return &Service{config: config}
 }
 
@@ -42,6 +90,8 @@ type GoogleTokenInfo struct {
 	Name     string
 	Picture  string
 	Verified bool
+	// HostedDomain is the Google Workspace domain ("hd" claim), empty for consumer accounts
+	HostedDomain string
 }
 
 // ValidateGoogleIDToken validates a Google ID token and extracts user information
@@ -50,39 +100,41 @@ func (s *Service) ValidateGoogleIDToken(ctx context.Context, idToken string) (*G
 	// 1. Token signature (signed by Google)
 	// 2. Token expiration
 	// 3. Audience claim (aud) matches our Client ID
-	payload, err := idtoken.Validate(ctx, idToken, s.config.GoogleClientID)
+	claims, err := s.config.GoogleTokenVerifier.Verify(ctx, idToken, s.config.GoogleClientID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate Google ID token: %w", err)
 	}
 
 	// Additional validation: verify issuer is Google
-	issuer, _ := payload.Claims["iss"].(string)
+	issuer, _ := claims["iss"].(string)
 	if issuer != "https://accounts.google.com" && issuer != "accounts.google.com" {
 		return nil, fmt.Errorf("invalid issuer: %s", issuer)
 	}
 
 	// Verify audience explicitly (belt and suspenders)
-	audience, _ := payload.Claims["aud"].(string)
+	audience, _ := claims["aud"].(string)
 	if audience != s.config.GoogleClientID {
 		return nil, fmt.Errorf("invalid audience: expected %s, got %s", s.config.GoogleClientID, audience)
 	}
 
-	email, _ := payload.Claims["email"].(string)
-	sub, _ := payload.Claims["sub"].(string)
-	name, _ := payload.Claims["name"].(string)
-	picture, _ := payload.Claims["picture"].(string)
-	emailVerified, _ := payload.Claims["email_verified"].(bool)
+	email, _ := claims["email"].(string)
+	sub, _ := claims["sub"].(string)
+	name, _ := claims["name"].(string)
+	picture, _ := claims["picture"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	hostedDomain, _ := claims["hd"].(string)
 
 	if email == "" || sub == "" {
 		return nil, ErrInvalidToken
 	}
 
 	return &GoogleTokenInfo{
-		Email:    email,
-		GoogleID: sub,
-		Name:     name,
-		Picture:  picture,
-		Verified: emailVerified,
+		Email:        email,
+		GoogleID:     sub,
+		Name:         name,
+		Picture:      picture,
+		Verified:     emailVerified,
+		HostedDomain: hostedDomain,
 	}, nil
 }
 
@@ -90,6 +142,8 @@ func (s *Service) ValidateGoogleIDToken(ctx context.Context, idToken string) (*G
 type JWTClaims struct {
 	UserID int64  `json:"user_id"`
 	Email  string `json:"email"`
+	// Scopes restricts the token to procedures requiring only these scopes; empty means unrestricted
+	Scopes []string `json:"scopes,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -119,6 +173,9 @@ func (s *Service) ValidateJWT(tokenString string) (*JWTClaims, error) {
 		return s.config.JWTSecret, nil
 	})
 
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExpired, err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
 	}
@@ -130,6 +187,11 @@ func (s *Service) ValidateJWT(tokenString string) (*JWTClaims, error) {
 	return nil, ErrInvalidToken
 }
 
+// IsAdmin reports whether email belongs to a configured administrator
+func (s *Service) IsAdmin(email string) bool {
+	return containsFold(s.config.AdminEmails, normalizeEmail(email))
+}
+
 // GenerateRandomSecret generates a random secret for JWT signing (useful for development)
 func GenerateRandomSecret() ([]byte, error) {
 	secret := make([]byte, 32)
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+
+	"connectrpc.com/connect"
+)
+
+// ClientInterceptor attaches credentials to outgoing requests made by Go clients
+type ClientInterceptor struct {
+	token string
+}
+
+// NewClientInterceptor creates a client interceptor that sends token (a JWT or an API key)
+// as a bearer credential on every call
+func NewClientInterceptor(token string) *ClientInterceptor {
+	return &ClientInterceptor{token: token}
+}
+
+// WrapUnary adds the Authorization header to unary client calls
+func (c *ClientInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			req.Header().Set("Authorization", "Bearer "+c.token)
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient adds the Authorization header to streaming client calls
+func (c *ClientInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		conn.RequestHeader().Set("Authorization", "Bearer "+c.token)
+		return conn
+	}
+}
+
+// WrapStreamingHandler is a no-op: ClientInterceptor only applies to clients
+func (c *ClientInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
@@ -2,75 +2,226 @@ package auth
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"net/http"
 	"strings"
 
 	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/internal/apierror"
+	"github.com/damejeras/goose/internal/logging"
 )
 
-// Interceptor is a Connect RPC interceptor that validates JWT tokens
+// APIKeyValidator authenticates requests that carry an API key instead of a JWT
+type APIKeyValidator interface {
+	// IsAPIKey reports whether the bearer credential has the API key format
+	IsAPIKey(token string) bool
+	// ValidateAPIKey returns the user owning the key
+	ValidateAPIKey(ctx context.Context, key string) (Identity, error)
+}
+
+// Identity is the user an API key or client certificate authenticates as
+type Identity struct {
+	UserID int64
+	Email  string
+	// Scopes limits the credential to procedures requiring only these scopes; nil grants every scope
+	Scopes []string
+}
+
+// ServicePrincipalResolver authenticates services by their verified client certificate
+type ServicePrincipalResolver interface {
+	// ResolveServicePrincipal returns the user the certificate acts as, or false when it isn't mapped
+	ResolveServicePrincipal(ctx context.Context, cert *x509.Certificate) (Identity, bool, error)
+}
+
+// Interceptor is a Connect RPC interceptor that enforces the declared method
+// policies by validating JWT tokens, API keys and client certificates
 type Interceptor struct {
 	authService *Service
-	publicMethods map[string]bool
+	apiKeys     APIKeyValidator
+	services    ServicePrincipalResolver
+	policies    MethodPolicies
 }
 
-// NewInterceptor creates a new auth interceptor
-func NewInterceptor(authService *Service, publicMethods []string) *Interceptor {
-	publicMap := make(map[string]bool)
-	for _, method := range publicMethods {
-		publicMap[method] = true
-	}
+// NewInterceptor creates a new auth interceptor.
+// apiKeys may be nil, in which case only JWTs are accepted; services may be
+// nil when client certificates don't authenticate callers.
+func NewInterceptor(authService *Service, apiKeys APIKeyValidator, services ServicePrincipalResolver, policies MethodPolicies) *Interceptor {
 	return &Interceptor{
-		authService:   authService,
-		publicMethods: publicMap,
+		authService: authService,
+		apiKeys:     apiKeys,
+		services:    services,
+		policies:    policies,
 	}
 }
 
 type contextKey string
 
-const UserIDContextKey contextKey = "user_id"
+const (
+	UserIDContextKey contextKey = "user_id"
+	ScopesContextKey contextKey = "scopes"
+)
 
 // WrapUnary wraps unary RPC calls with authentication
 func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-		// Check if this is a public method
-		procedure := req.Spec().Procedure
-		if i.publicMethods[procedure] {
-			return next(ctx, req)
+		ctx, err := i.authorize(ctx, req.Header(), req.Spec())
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient is a no-op: Interceptor guards handlers, see ClientInterceptor for clients
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler wraps streaming handler calls with authentication.
+// Credentials are checked once, from the request headers, before the handler runs.
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := i.authorize(ctx, conn.RequestHeader(), conn.Spec())
+		if err != nil {
+			return err
 		}
+		return next(ctx, conn)
+	}
+}
+
+// principal is the authenticated caller of a procedure
+type principal struct {
+	userID int64
+	email  string
+	// scopes is nil for unscoped credentials, which are granted every scope
+	scopes []string
+}
+
+// authorize applies the procedure's policy and returns a context carrying the caller's user ID
+func (i *Interceptor) authorize(ctx context.Context, header http.Header, spec connect.Spec) (context.Context, error) {
+	policy, ok := i.policies[spec.Procedure]
+	if !ok {
+		return nil, apierror.PermissionDenied(ReasonNoMethodPolicy, ErrNoMethodPolicy)
+	}
+	if policy.Public {
+		return ctx, nil
+	}
 
-		// Extract token from Authorization header
-		auth := req.Header().Get("Authorization")
-		if auth == "" {
-			return nil, connect.NewError(connect.CodeUnauthenticated, ErrMissingToken)
+	caller, err := i.authenticate(ctx, header, spec)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.allows(caller.scopes) {
+		return nil, apierror.PermissionDenied(ReasonInsufficientScopes, ErrInsufficientScopes)
+	}
+	if len(policy.Roles) > 0 && !i.holdsRole(caller, policy.Roles) {
+		return nil, apierror.PermissionDenied(ReasonRoleRequired, ErrRoleRequired)
+	}
+
+	// Add user ID to context and the request logger
+	ctx = logging.AddAttrs(ctx, slog.Int64("user_id", caller.userID))
+	ctx = context.WithValue(ctx, ScopesContextKey, caller.scopes)
+	return context.WithValue(ctx, UserIDContextKey, caller.userID), nil
+}
+
+// holdsRole reports whether the caller holds at least one of roles
+func (i *Interceptor) holdsRole(caller principal, roles []v1.Role) bool {
+	for _, role := range roles {
+		if role == v1.Role_ROLE_ADMIN && i.authService.IsAdmin(caller.email) {
+			return true
 		}
+	}
+	return false
+}
 
-		// Remove "Bearer " prefix
-		token := strings.TrimPrefix(auth, "Bearer ")
-		if token == auth {
-			return nil, connect.NewError(connect.CodeUnauthenticated, ErrInvalidToken)
+// authenticate validates the bearer token (JWT or API key). Without an
+// Authorization header it falls back to a mapped client certificate, then to
+// the session cookie. Cookie-authenticated calls to procedures that may have
+// side effects must also carry a matching CSRF token.
+//
+// Browsers present client certificates on every request, including forged
+// cross-site ones, so certificates only authenticate requests that don't look
+// like they came from a browser; see browserRequest.
+func (i *Interceptor) authenticate(ctx context.Context, header http.Header, spec connect.Spec) (principal, error) {
+	auth := header.Get("Authorization")
+	if auth == "" {
+		if cert, ok := ClientCertificate(ctx); ok && i.services != nil && !browserRequest(header) {
+			identity, mapped, err := i.services.ResolveServicePrincipal(ctx, cert)
+			if err != nil {
+				return principal{}, credentialError(err)
+			}
+			if mapped {
+				return principal{userID: identity.UserID, email: identity.Email, scopes: identity.Scopes}, nil
+			}
+		}
+		if i.authService.CookieSessionsEnabled() {
+			if token, ok := sessionCookie(header); ok {
+				return i.authenticateCookie(header, spec, token)
+			}
 		}
+		return principal{}, apierror.Unauthenticated(ReasonMissingToken, ErrMissingToken)
+	}
 
-		// Validate JWT
-		claims, err := i.authService.ValidateJWT(token)
+	// Remove "Bearer " prefix
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == auth {
+		return principal{}, apierror.Unauthenticated(ReasonInvalidToken, ErrInvalidToken)
+	}
+
+	if i.apiKeys != nil && i.apiKeys.IsAPIKey(token) {
+		owner, err := i.apiKeys.ValidateAPIKey(ctx, token)
 		if err != nil {
-			return nil, connect.NewError(connect.CodeUnauthenticated, err)
+			return principal{}, credentialError(err)
 		}
+		return principal{userID: owner.UserID, email: owner.Email, scopes: owner.Scopes}, nil
+	}
 
-		// Add user ID to context
-		ctx = context.WithValue(ctx, UserIDContextKey, claims.UserID)
-
-		return next(ctx, req)
+	// Validate JWT
+	claims, err := i.authService.ValidateJWT(token)
+	if err != nil {
+		return principal{}, credentialError(err)
 	}
+	return principal{userID: claims.UserID, email: claims.Email, scopes: claims.Scopes}, nil
 }
 
-// WrapStreamingClient wraps streaming client calls with authentication
-func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
-	return next // no-op for now, can add auth logic later if needed
+// browserRequest reports whether a request carries cookies or the headers
+// browsers add to fetches, which non-browser clients don't send
+func browserRequest(header http.Header) bool {
+	return header.Get("Cookie") != "" || header.Get("Origin") != "" || header.Get("Sec-Fetch-Site") != ""
 }
 
-// WrapStreamingHandler wraps streaming handler calls with authentication
-func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
-	return next // no-op for now, can add auth logic later if needed
+func (i *Interceptor) authenticateCookie(header http.Header, spec connect.Spec, token string) (principal, error) {
+	claims, err := i.authService.ValidateJWT(token)
+	if err != nil {
+		return principal{}, credentialError(err)
+	}
+
+	if spec.IdempotencyLevel != connect.IdempotencyNoSideEffects {
+		if err := i.authService.ValidateCSRFToken(token, header.Get(CSRFHeader)); err != nil {
+			return principal{}, apierror.PermissionDenied(ReasonInvalidCSRFToken, ErrInvalidCSRFToken)
+		}
+	}
+	return principal{userID: claims.UserID, email: claims.Email, scopes: claims.Scopes}, nil
+}
+
+// credentialError reports a rejected JWT, API key or client certificate.
+// Clients learn whether the token expired, but not what else was wrong with it.
+func credentialError(err error) error {
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return apierror.Unauthenticated(ReasonTokenExpired, ErrTokenExpired).WithCause(err)
+	case errors.Is(err, ErrUnknownServicePrincipal):
+		return apierror.Unauthenticated(ReasonUnknownServicePrincipal, ErrUnknownServicePrincipal).WithCause(err)
+	case err == ErrInvalidToken:
+		return apierror.Unauthenticated(ReasonInvalidToken, ErrInvalidToken)
+	case errors.Is(err, ErrInvalidToken):
+		return apierror.Unauthenticated(ReasonInvalidToken, ErrInvalidToken).WithCause(err)
+	default:
+		return apierror.Internal("failed to validate credentials", err)
+	}
 }
 
 // GetUserIDFromContext extracts the user ID from the context
@@ -78,3 +229,10 @@ func GetUserIDFromContext(ctx context.Context) (int64, bool) {
 	userID, ok := ctx.Value(UserIDContextKey).(int64)
 	return userID, ok
 }
+
+// GetScopesFromContext returns the scopes the caller's credential is limited to,
+// or nil when it is unscoped and has every scope
+func GetScopesFromContext(ctx context.Context) []string {
+	scopes, _ := ctx.Value(ScopesContextKey).([]string)
+	return scopes
+}
@@ -0,0 +1,238 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/internal/apierror"
+	"github.com/damejeras/goose/internal/auth"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	watchProcedure       = "/test.v1.StreamService/Watch"
+	publicWatchProcedure = "/test.v1.StreamService/PublicWatch"
+	adminWatchProcedure  = "/test.v1.StreamService/AdminWatch"
+)
+
+// apiKeys accepts keys prefixed with "key_" as the user with the ID 2
+type apiKeys struct{}
+
+func (apiKeys) IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, "key_")
+}
+
+func (apiKeys) ValidateAPIKey(ctx context.Context, key string) (auth.Identity, error) {
+	if key != "key_valid" {
+		return auth.Identity{}, auth.ErrInvalidToken
+	}
+	return auth.Identity{UserID: 2, Email: "service@example.com"}, nil
+}
+
+// servicePrincipals maps every client certificate to the user with the ID 3
+type servicePrincipals struct{}
+
+func (servicePrincipals) ResolveServicePrincipal(ctx context.Context, cert *x509.Certificate) (auth.Identity, bool, error) {
+	return auth.Identity{UserID: 3, Email: "ci@example.com"}, true, nil
+}
+
+// newStreamServer serves a server-streaming procedure that sends the caller's
+// user ID, or 0 for anonymous callers, behind the auth interceptor. With
+// services set, every request presents a verified client certificate.
+func newStreamServer(t *testing.T, authService *auth.Service, services auth.ServicePrincipalResolver) *httptest.Server {
+	t.Helper()
+
+	policies := auth.MethodPolicies{
+		watchProcedure:       {},
+		publicWatchProcedure: {Public: true},
+		adminWatchProcedure:  {Roles: []v1.Role{v1.Role_ROLE_ADMIN}},
+	}
+	interceptors := connect.WithInterceptors(
+		apierror.NewInterceptor(slog.New(slog.DiscardHandler)),
+		auth.NewInterceptor(authService, apiKeys{}, services, policies),
+	)
+	watch := func(ctx context.Context, req *connect.Request[emptypb.Empty], stream *connect.ServerStream[wrapperspb.Int64Value]) error {
+		userID, _ := auth.GetUserIDFromContext(ctx)
+		for range 3 {
+			if err := stream.Send(wrapperspb.Int64(userID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(watchProcedure, connect.NewServerStreamHandler(watchProcedure, watch, interceptors))
+	mux.Handle(publicWatchProcedure, connect.NewServerStreamHandler(publicWatchProcedure, watch, interceptors))
+	mux.Handle(adminWatchProcedure, connect.NewServerStreamHandler(adminWatchProcedure, watch, interceptors))
+	var handler http.Handler = mux
+	if services != nil {
+		certificates := auth.ClientCertificateMiddleware(mux)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "ci"}}
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+			certificates.ServeHTTP(w, r)
+		})
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestInterceptorStreaming(t *testing.T) {
+	authService := auth.NewService(auth.Config{
+		JWTSecret:   []byte("0123456789abcdef0123456789abcdef"),
+		AdminEmails: []string{"admin@example.com"},
+	})
+	server := newStreamServer(t, authService, nil)
+
+	jwt, err := authService.GenerateJWT(1, "user@example.com")
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	adminJWT, err := authService.GenerateJWT(4, "Admin@example.com")
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		procedure  string
+		token      string
+		wantUserID int64
+		wantCode   connect.Code
+	}{
+		{name: "no token", procedure: watchProcedure, wantCode: connect.CodeUnauthenticated},
+		{name: "invalid JWT", procedure: watchProcedure, token: "not-a-jwt", wantCode: connect.CodeUnauthenticated},
+		{name: "valid JWT", procedure: watchProcedure, token: jwt, wantUserID: 1},
+		{name: "invalid API key", procedure: watchProcedure, token: "key_revoked", wantCode: connect.CodeUnauthenticated},
+		{name: "API key", procedure: watchProcedure, token: "key_valid", wantUserID: 2},
+		{name: "public method without token", procedure: publicWatchProcedure},
+		{name: "admin method without role", procedure: adminWatchProcedure, token: jwt, wantCode: connect.CodePermissionDenied},
+		{name: "admin method with role", procedure: adminWatchProcedure, token: adminJWT, wantUserID: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []connect.ClientOption
+			if tt.token != "" {
+				opts = append(opts, connect.WithInterceptors(auth.NewClientInterceptor(tt.token)))
+			}
+			client := connect.NewClient[emptypb.Empty, wrapperspb.Int64Value](server.Client(), server.URL+tt.procedure, opts...)
+
+			stream, err := client.CallServerStream(t.Context(), connect.NewRequest(&emptypb.Empty{}))
+			if err != nil {
+				t.Fatalf("CallServerStream: %v", err)
+			}
+			defer stream.Close()
+
+			var received int
+			for stream.Receive() {
+				received++
+				if got := stream.Msg().GetValue(); got != tt.wantUserID {
+					t.Errorf("user ID = %d, want %d", got, tt.wantUserID)
+				}
+			}
+
+			if tt.wantCode != 0 {
+				var connectErr *connect.Error
+				if !errors.As(stream.Err(), &connectErr) || connectErr.Code() != tt.wantCode {
+					t.Fatalf("error = %v, want code %s", stream.Err(), tt.wantCode)
+				}
+				if received != 0 {
+					t.Errorf("received %d messages before the error, want 0", received)
+				}
+				return
+			}
+			if err := stream.Err(); err != nil {
+				t.Fatalf("stream error: %v", err)
+			}
+			if received != 3 {
+				t.Errorf("received %d messages, want 3", received)
+			}
+		})
+	}
+}
+
+func TestInterceptorClientCertificate(t *testing.T) {
+	authService := auth.NewService(auth.Config{
+		JWTSecret: []byte("0123456789abcdef0123456789abcdef"),
+		Cookies:   auth.CookieConfig{Enabled: true},
+	})
+	server := newStreamServer(t, authService, servicePrincipals{})
+
+	session, err := authService.GenerateJWT(1, "user@example.com")
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantUserID int64
+		wantCode   connect.Code
+	}{
+		{name: "non-browser client", header: http.Header{}, wantUserID: 3},
+		{name: "bearer token takes precedence", header: http.Header{"Authorization": {"Bearer " + session}}, wantUserID: 1},
+		{name: "cross-origin fetch", header: http.Header{"Origin": {"https://evil.example"}}, wantCode: connect.CodeUnauthenticated},
+		{name: "browser navigation", header: http.Header{"Sec-Fetch-Site": {"cross-site"}}, wantCode: connect.CodeUnauthenticated},
+		{name: "session cookie without CSRF token", header: http.Header{"Cookie": {auth.SessionCookieName + "=" + session}}, wantCode: connect.CodePermissionDenied},
+		{
+			name: "session cookie with CSRF token",
+			header: http.Header{
+				"Cookie":        {auth.SessionCookieName + "=" + session},
+				auth.CSRFHeader: {authService.CSRFToken(session)},
+			},
+			wantUserID: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := connect.NewClient[emptypb.Empty, wrapperspb.Int64Value](server.Client(), server.URL+watchProcedure)
+			req := connect.NewRequest(&emptypb.Empty{})
+			for key, values := range tt.header {
+				req.Header()[key] = values
+			}
+
+			stream, err := client.CallServerStream(t.Context(), req)
+			if err != nil {
+				t.Fatalf("CallServerStream: %v", err)
+			}
+			defer stream.Close()
+
+			var received int
+			for stream.Receive() {
+				received++
+				if got := stream.Msg().GetValue(); got != tt.wantUserID {
+					t.Errorf("user ID = %d, want %d", got, tt.wantUserID)
+				}
+			}
+
+			if tt.wantCode != 0 {
+				var connectErr *connect.Error
+				if !errors.As(stream.Err(), &connectErr) || connectErr.Code() != tt.wantCode {
+					t.Fatalf("error = %v, want code %s", stream.Err(), tt.wantCode)
+				}
+				return
+			}
+			if err := stream.Err(); err != nil {
+				t.Fatalf("stream error: %v", err)
+			}
+			if received != 3 {
+				t.Errorf("received %d messages, want 3", received)
+			}
+		})
+	}
+}
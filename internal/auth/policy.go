@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var (
+	ErrNoMethodPolicy      = errors.New("procedure has no declared auth policy")
+	ErrInsufficientScopes  = errors.New("credential lacks required scopes")
+	ErrRoleRequired        = errors.New("procedure requires a role the caller doesn't hold")
+	ErrInvalidMethodPolicy = errors.New("invalid auth policy")
+)
+
+// MethodPolicy is the access policy a procedure declares with the (api.v1.auth) method option
+type MethodPolicy struct {
+	Public bool
+	Scopes []string
+	// Roles restricts the procedure to callers holding at least one of them, e.g.
+	// ROLE_ADMIN for users listed in Config.AdminEmails. The server sets them
+	// itself for infrastructure services such as reflection.
+	Roles []v1.Role
+}
+
+// MethodPolicies maps Connect procedure names ("/api.v1.AuthService/Login") to their policies
+type MethodPolicies map[string]MethodPolicy
+
+// LoadMethodPolicies reads the (api.v1.auth) option of every method in the services
+// declared by files. It fails if any method has no policy, so a new procedure can't
+// be shipped without deciding who may call it.
+func LoadMethodPolicies(files ...protoreflect.FileDescriptor) (MethodPolicies, error) {
+	policies := make(MethodPolicies)
+	var errs []error
+
+	for _, file := range files {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			service := services.Get(i)
+			methods := service.Methods()
+			for j := 0; j < methods.Len(); j++ {
+				method := methods.Get(j)
+				procedure := fmt.Sprintf("/%s/%s", service.FullName(), method.Name())
+
+				policy, ok := proto.GetExtension(method.Options(), v1.E_Auth).(*v1.AuthPolicy)
+				if !ok || policy == nil {
+					errs = append(errs, fmt.Errorf("%s: %w", procedure, ErrNoMethodPolicy))
+					continue
+				}
+
+				var loaded MethodPolicy
+				switch policy.GetLevel() {
+				case v1.AuthLevel_AUTH_LEVEL_PUBLIC:
+					loaded = MethodPolicy{Public: true, Scopes: policy.GetScopes(), Roles: policy.GetRoles()}
+				case v1.AuthLevel_AUTH_LEVEL_AUTHENTICATED:
+					loaded = MethodPolicy{Scopes: policy.GetScopes(), Roles: policy.GetRoles()}
+				default:
+					errs = append(errs, fmt.Errorf("%s: %w: level %s", procedure, ErrNoMethodPolicy, policy.GetLevel()))
+					continue
+				}
+				if err := loaded.validate(); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", procedure, err))
+					continue
+				}
+				policies[procedure] = loaded
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// Check reports every procedure without a valid policy in the services mounted
+// at paths, e.g. "/api.v1.AuthService/". The server calls it with every handler
+// it serves behind the interceptor, including ones whose policies it sets itself
+// such as reflection, so none can start out rejecting every call. Services are
+// looked up in the global registry, so their generated code must be linked in.
+func (p MethodPolicies) Check(paths ...string) error {
+	var errs []error
+	for _, path := range paths {
+		name := protoreflect.FullName(strings.Trim(path, "/"))
+		descriptor, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		service, ok := descriptor.(protoreflect.ServiceDescriptor)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s is not a service", path, name))
+			continue
+		}
+
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			procedure := fmt.Sprintf("/%s/%s", service.FullName(), methods.Get(i).Name())
+			policy, ok := p[procedure]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: %w", procedure, ErrNoMethodPolicy))
+				continue
+			}
+			if err := policy.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", procedure, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Scopes returns every scope required by some policy, sorted
+func (p MethodPolicies) Scopes() []string {
+	var scopes []string
+	for _, policy := range p {
+		scopes = append(scopes, policy.Scopes...)
+	}
+	slices.Sort(scopes)
+	return slices.Compact(scopes)
+}
+
+// validate rejects roles on public procedures, which have no caller to hold
+// them, and roles the interceptor doesn't know how to check
+func (p MethodPolicy) validate() error {
+	if p.Public && len(p.Roles) > 0 {
+		return fmt.Errorf("%w: a public procedure can't require roles", ErrInvalidMethodPolicy)
+	}
+	for _, role := range p.Roles {
+		if role != v1.Role_ROLE_ADMIN {
+			return fmt.Errorf("%w: unknown role %s", ErrInvalidMethodPolicy, role)
+		}
+	}
+	return nil
+}
+
+// allows reports whether a credential with the given scopes satisfies the policy.
+// Unscoped credentials (nil scopes) are granted every scope.
+func (p MethodPolicy) allows(scopes []string) bool {
+	if scopes == nil {
+		return true
+	}
+	granted := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		granted[scope] = true
+	}
+	for _, scope := range p.Scopes {
+		if !granted[scope] {
+			return false
+		}
+	}
+	return true
+}
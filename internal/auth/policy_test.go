@@ -0,0 +1,130 @@
+package auth_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/api/gen/go/v1/v1connect"
+	"github.com/damejeras/goose/internal/auth"
+	_ "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// policyFile builds a file declaring test.v1.TestService with one Call method
+// carrying policy
+func policyFile(t *testing.T, policy *v1.AuthPolicy) protoreflect.FileDescriptor {
+	t.Helper()
+
+	options := &descriptorpb.MethodOptions{}
+	proto.SetExtension(options, v1.E_Auth, policy)
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/policy.proto"),
+		Package:    proto.String("test.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/empty.proto"},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("TestService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Call"),
+				InputType:  proto.String(".google.protobuf.Empty"),
+				OutputType: proto.String(".google.protobuf.Empty"),
+				Options:    options,
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("NewFile: %v", err)
+	}
+	return file
+}
+
+func TestLoadMethodPoliciesRoles(t *testing.T) {
+	const procedure = "/test.v1.TestService/Call"
+	admin := []v1.Role{v1.Role_ROLE_ADMIN}
+
+	policies, err := auth.LoadMethodPolicies(policyFile(t, &v1.AuthPolicy{Level: v1.AuthLevel_AUTH_LEVEL_AUTHENTICATED, Roles: admin}))
+	if err != nil {
+		t.Fatalf("LoadMethodPolicies: %v", err)
+	}
+	if got := policies[procedure].Roles; !slices.Equal(got, admin) {
+		t.Errorf("roles = %v, want %v", got, admin)
+	}
+
+	tests := []struct {
+		name   string
+		policy *v1.AuthPolicy
+	}{
+		{name: "public with roles", policy: &v1.AuthPolicy{Level: v1.AuthLevel_AUTH_LEVEL_PUBLIC, Roles: admin}},
+		{name: "unspecified role", policy: &v1.AuthPolicy{Level: v1.AuthLevel_AUTH_LEVEL_AUTHENTICATED, Roles: []v1.Role{v1.Role_ROLE_UNSPECIFIED}}},
+		{name: "unknown role", policy: &v1.AuthPolicy{Level: v1.AuthLevel_AUTH_LEVEL_AUTHENTICATED, Roles: []v1.Role{99}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := auth.LoadMethodPolicies(policyFile(t, tt.policy)); !errors.Is(err, auth.ErrInvalidMethodPolicy) {
+				t.Fatalf("LoadMethodPolicies: %v, want %v", err, auth.ErrInvalidMethodPolicy)
+			}
+		})
+	}
+}
+
+func TestMethodPoliciesCheck(t *testing.T) {
+	policies, err := auth.LoadMethodPolicies(v1.File_v1_auth_proto, v1.File_v1_apikey_proto)
+	if err != nil {
+		t.Fatalf("LoadMethodPolicies: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		wantErr error
+	}{
+		{name: "services with declared policies", paths: []string{"/" + v1connect.AuthServiceName + "/", "/" + v1connect.APIKeyServiceName + "/"}},
+		{name: "service from a file that wasn't loaded", paths: []string{"/" + v1connect.DeviceAuthServiceName + "/"}, wantErr: auth.ErrNoMethodPolicy},
+		{name: "infrastructure service without a policy", paths: []string{"/grpc.reflection.v1.ServerReflection/"}, wantErr: auth.ErrNoMethodPolicy},
+		{name: "unregistered service", paths: []string{"/test.v1.Missing/"}, wantErr: protoregistry.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policies.Check(tt.paths...)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("Check: %v", err)
+			case tt.wantErr != nil && err == nil:
+				t.Fatal("Check succeeded, want an error")
+			case !errors.Is(err, tt.wantErr):
+				t.Fatalf("Check: %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	policies["/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"] = auth.MethodPolicy{Roles: []v1.Role{v1.Role_ROLE_ADMIN}}
+	if err := policies.Check("/grpc.reflection.v1.ServerReflection/"); err != nil {
+		t.Fatalf("Check after adding the reflection policy: %v", err)
+	}
+
+	// policies the server sets itself are validated like declared ones
+	policies["/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"] = auth.MethodPolicy{Public: true, Roles: []v1.Role{v1.Role_ROLE_ADMIN}}
+	if err := policies.Check("/grpc.reflection.v1.ServerReflection/"); !errors.Is(err, auth.ErrInvalidMethodPolicy) {
+		t.Fatalf("Check of a public policy with roles: %v, want %v", err, auth.ErrInvalidMethodPolicy)
+	}
+}
+
+func TestMethodPoliciesScopes(t *testing.T) {
+	policies, err := auth.LoadMethodPolicies(v1.File_v1_auth_proto, v1.File_v1_apikey_proto)
+	if err != nil {
+		t.Fatalf("LoadMethodPolicies: %v", err)
+	}
+
+	want := []string{"api_keys:read", "api_keys:write", "user:read"}
+	if got := policies.Scopes(); !slices.Equal(got, want) {
+		t.Errorf("Scopes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/damejeras/goose/db/sqlc"
+)
+
+var ErrUnknownServicePrincipal = errors.New("client certificate is mapped to an unknown user")
+
+type clientCertKey struct{}
+
+// ClientCertificateMiddleware makes the verified client certificate of a TLS
+// request available to the interceptor. Unverified certificates are ignored.
+func ClientCertificateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), clientCertKey{}, r.TLS.VerifiedChains[0][0]))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientCertificate returns the verified client certificate of the request
+func ClientCertificate(ctx context.Context) (*x509.Certificate, bool) {
+	cert, ok := ctx.Value(clientCertKey{}).(*x509.Certificate)
+	return cert, ok
+}
+
+// ServicePrincipals maps the subject common name of client certificates to the
+// email of the user account a service acts as
+type ServicePrincipals struct {
+	emails  map[string]string
+	queries *sqlc.Queries
+}
+
+// NewServicePrincipals creates a resolver for the given common name to email mapping
+func NewServicePrincipals(emails map[string]string, queries *sqlc.Queries) *ServicePrincipals {
+	return &ServicePrincipals{emails: emails, queries: queries}
+}
+
+// ResolveServicePrincipal looks up the user mapped to the certificate's common name
+func (p *ServicePrincipals) ResolveServicePrincipal(ctx context.Context, cert *x509.Certificate) (Identity, bool, error) {
+	email, ok := p.emails[cert.Subject.CommonName]
+	if !ok {
+		return Identity{}, false, nil
+	}
+
+	user, err := p.queries.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Identity{}, false, fmt.Errorf("%w: %s", ErrUnknownServicePrincipal, email)
+	}
+	if err != nil {
+		return Identity{}, false, fmt.Errorf("look up service principal %s: %w", email, err)
+	}
+	return Identity{UserID: user.ID, Email: user.Email}, true, nil
+}
@@ -8,6 +8,8 @@ import (
 	"connectrpc.com/connect"
 	v1 "github.com/damejeras/goose/api/gen/go/v1"
 	"github.com/damejeras/goose/db/sqlc"
+	"github.com/damejeras/goose/internal/apierror"
+	"github.com/damejeras/goose/internal/logging"
 )
 
 // Server implements the AuthService
@@ -31,13 +33,12 @@ func (s *Server) Login(ctx context.Context, req *connect.Request[v1.LoginRequest
 	// Validate Google ID token
 	tokenInfo, err := s.authService.ValidateGoogleIDToken(ctx, req.Msg.GoogleIdToken)
 	if err != nil {
-		s.logger.Error("failed to validate Google ID token", "error", err)
-		return nil, connect.NewError(connect.CodeUnauthenticated, err)
+		return nil, apierror.Unauthenticated(ReasonInvalidToken, ErrInvalidToken).WithCause(err)
 	}
 
 	if !tokenInfo.Verified {
-		s.logger.Warn("unverified email attempted login", "email", tokenInfo.Email)
-		return nil, connect.NewError(connect.CodePermissionDenied, ErrUnauthorized)
+		s.log(ctx).WarnContext(ctx, "unverified email attempted login", "email", tokenInfo.Email)
+		return nil, apierror.PermissionDenied(ReasonEmailNotVerified, ErrEmailNotVerified)
 	}
 
 	// Find or create user
@@ -47,6 +48,12 @@ func (s *Server) Login(ctx context.Context, req *connect.Request[v1.LoginRequest
 	})
 	if err != nil {
 		if err == sql.ErrNoRows {
+			// Enforce signup policy before creating an account
+			if err := s.checkSignup(ctx, tokenInfo); err != nil {
+				s.log(ctx).WarnContext(ctx, "signup rejected", "email", tokenInfo.Email, "hosted_domain", tokenInfo.HostedDomain, "error", err)
+				return nil, err
+			}
+
 			// Create new user
 			user, err = s.queries.CreateUser(ctx, sqlc.CreateUserParams{
 				Email: tokenInfo.Email,
@@ -57,13 +64,16 @@ func (s *Server) Login(ctx context.Context, req *connect.Request[v1.LoginRequest
 				Name: tokenInfo.Name,
 			})
 			if err != nil {
-				s.logger.Error("failed to create user", "error", err)
-				return nil, connect.NewError(connect.CodeInternal, err)
+				return nil, apierror.Internal("failed to create user", err)
 			}
-			s.logger.Info("new user created", "user_id", user.ID, "email", user.Email)
+			if s.authService.config.Signup.Mode == SignupInviteOnly {
+				if err := s.queries.AcceptInvitation(ctx, normalizeEmail(tokenInfo.Email)); err != nil {
+					s.log(ctx).WarnContext(ctx, "failed to mark invitation accepted", "user_id", user.ID, "error", err)
+				}
+			}
+			s.log(ctx).InfoContext(ctx, "new user created", "user_id", user.ID, "email", user.Email)
 		} else {
-			s.logger.Error("failed to find user", "error", err)
-			return nil, connect.NewError(connect.CodeInternal, err)
+			return nil, apierror.Internal("failed to find user", err)
 		}
 	} else {
 		// Update existing user's profile info and last login
@@ -71,26 +81,24 @@ func (s *Server) Login(ctx context.Context, req *connect.Request[v1.LoginRequest
 			Name: tokenInfo.Name,
 			ID:   user.ID,
 		}); err != nil {
-			s.logger.Warn("failed to update user profile", "user_id", user.ID, "error", err)
+			s.log(ctx).WarnContext(ctx, "failed to update user profile", "user_id", user.ID, "error", err)
 		}
 		// Refresh user data
 		user, err = s.queries.GetUser(ctx, user.ID)
 		if err != nil {
-			s.logger.Error("failed to get user after update", "error", err)
-			return nil, connect.NewError(connect.CodeInternal, err)
+			return nil, apierror.Internal("failed to get user", err)
 		}
 	}
 
 	// Generate JWT
 	jwt, err := s.authService.GenerateJWT(user.ID, user.Email)
 	if err != nil {
-		s.logger.Error("failed to generate JWT", "error", err)
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, apierror.Internal("failed to issue token", err)
 	}
 
-	s.logger.Info("user logged in", "user_id", user.ID, "email", user.Email)
+	s.log(ctx).InfoContext(ctx, "user logged in", "user_id", user.ID, "email", user.Email)
 
-	return connect.NewResponse(&v1.LoginResponse{
+	res := connect.NewResponse(&v1.LoginResponse{
 		Jwt: jwt,
 		User: &v1.User{
 			Id:       user.ID,
@@ -98,23 +106,58 @@ func (s *Server) Login(ctx context.Context, req *connect.Request[v1.LoginRequest
 			GoogleId: user.GoogleID.String,
 			Name:     user.Name,
 		},
-	}), nil
+	})
+
+	// In cookie mode the JWT never reaches scripts - it lives in an HttpOnly cookie
+	if s.authService.CookieSessionsEnabled() {
+		res.Msg.Jwt = ""
+		for _, cookie := range s.authService.SessionCookies(jwt) {
+			res.Header().Add("Set-Cookie", cookie.String())
+		}
+	}
+
+	return res, nil
+}
+
+// checkSignup applies the configured signup policy to a first-time login.
+// Rejections are returned as PermissionDenied errors with reason SIGNUP_REJECTED.
+func (s *Server) checkSignup(ctx context.Context, tokenInfo *GoogleTokenInfo) error {
+	policy := s.authService.config.Signup
+	switch policy.Mode {
+	case SignupOpen:
+		return nil
+	case SignupInviteOnly:
+		_, err := s.queries.GetPendingInvitationByEmail(ctx, normalizeEmail(tokenInfo.Email))
+		if err == sql.ErrNoRows {
+			return apierror.PermissionDenied(ReasonSignupRejected, ErrSignupNotInvited)
+		}
+		if err != nil {
+			return apierror.Internal("failed to find invitation", err)
+		}
+		return nil
+	case SignupDomains:
+		if err := policy.checkDomains(tokenInfo); err != nil {
+			return apierror.PermissionDenied(ReasonSignupRejected, err)
+		}
+		return nil
+	default:
+		return apierror.PermissionDenied(ReasonSignupRejected, ErrUnknownSignupPolicyMode)
+	}
 }
 
 // GetCurrentUser returns the current authenticated user
 func (s *Server) GetCurrentUser(ctx context.Context, req *connect.Request[v1.GetCurrentUserRequest]) (*connect.Response[v1.GetCurrentUserResponse], error) {
 	userID, ok := GetUserIDFromContext(ctx)
 	if !ok {
-		return nil, connect.NewError(connect.CodeUnauthenticated, ErrUnauthorized)
+		return nil, apierror.Unauthenticated(ReasonMissingToken, ErrUnauthorized)
 	}
 
 	user, err := s.queries.GetUser(ctx, userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, connect.NewError(connect.CodeNotFound, err)
+			return nil, apierror.NotFound(ReasonUserNotFound, ErrUserNotFound)
 		}
-		s.logger.Error("failed to get user", "user_id", userID, "error", err)
-		return nil, connect.NewError(connect.CodeInternal, err)
+		return nil, apierror.Internal("failed to get user", err)
 	}
 
 	return connect.NewResponse(&v1.GetCurrentUserResponse{
@@ -129,14 +172,27 @@ func (s *Server) GetCurrentUser(ctx context.Context, req *connect.Request[v1.Get
 
 // Logout handles user logout (currently just returns success)
 func (s *Server) Logout(ctx context.Context, req *connect.Request[v1.LogoutRequest]) (*connect.Response[v1.LogoutResponse], error) {
-	// With JWT, logout is handled client-side by removing the token
+	// With bearer JWTs, logout is handled client-side by removing the token;
+	// in cookie mode the session cookies are expired below
 	// Optionally, you could implement token blacklisting here
-	userID, ok := GetUserIDFromContext(ctx)
-	if ok {
-		s.logger.Info("user logged out", "user_id", userID)
+	if _, ok := GetUserIDFromContext(ctx); ok {
+		s.log(ctx).InfoContext(ctx, "user logged out")
 	}
 
-	return connect.NewResponse(&v1.LogoutResponse{
+	res := connect.NewResponse(&v1.LogoutResponse{
 		Success: true,
-	}), nil
+	})
+
+	if s.authService.CookieSessionsEnabled() {
+		for _, cookie := range s.authService.ClearSessionCookies() {
+			res.Header().Add("Set-Cookie", cookie.String())
+		}
+	}
+
+	return res, nil
+}
+
+// log returns the request-scoped logger
+func (s *Server) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
 }
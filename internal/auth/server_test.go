@@ -0,0 +1,188 @@
+package auth_test
+
+import (
+	"database/sql"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/db"
+	"github.com/damejeras/goose/db/sqlc"
+	"github.com/damejeras/goose/internal/apierror"
+	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/auth/authtest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testClientID = "test-client.apps.googleusercontent.com"
+
+// loginTest is an auth server backed by a fresh database and a local ID token issuer
+type loginTest struct {
+	server      *auth.Server
+	authService *auth.Service
+	queries     *sqlc.Queries
+	issuer      *authtest.TokenIssuer
+}
+
+func newLoginTest(t *testing.T, signup auth.SignupPolicy) *loginTest {
+	t.Helper()
+
+	logger := slog.New(slog.DiscardHandler)
+	database, err := db.Open(t.Context(), logger, filepath.Join(t.TempDir(), "goose.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	issuer, err := authtest.NewTokenIssuer()
+	if err != nil {
+		t.Fatalf("NewTokenIssuer: %v", err)
+	}
+	authService := auth.NewService(auth.Config{
+		GoogleClientID:      testClientID,
+		JWTSecret:           []byte("0123456789abcdef0123456789abcdef"),
+		Signup:              signup,
+		GoogleTokenVerifier: issuer,
+	})
+	queries := sqlc.New(database)
+
+	return &loginTest{
+		server:      auth.NewServer(authService, queries, logger),
+		authService: authService,
+		queries:     queries,
+		issuer:      issuer,
+	}
+}
+
+// login signs claims and logs in with them
+func (lt *loginTest) login(t *testing.T, claims jwt.MapClaims) (*v1.LoginResponse, error) {
+	t.Helper()
+
+	idToken, err := lt.issuer.Sign(claims)
+	if err != nil {
+		t.Fatalf("sign ID token: %v", err)
+	}
+	res, err := lt.server.Login(t.Context(), connect.NewRequest(&v1.LoginRequest{GoogleIdToken: idToken}))
+	if err != nil {
+		return nil, err
+	}
+	return res.Msg, nil
+}
+
+// assertAPIError fails unless err is an *apierror.Error with the code and reason
+func assertAPIError(t *testing.T, err error, code connect.Code, reason string) {
+	t.Helper()
+
+	apiErr, ok := apierror.From(err)
+	if !ok {
+		t.Fatalf("error = %v, want an API error with reason %s", err, reason)
+	}
+	if apiErr.Code() != code || apiErr.Reason() != reason {
+		t.Fatalf("error = %s %s, want %s %s", apiErr.Code(), apiErr.Reason(), code, reason)
+	}
+}
+
+func TestLoginCreatesUser(t *testing.T) {
+	lt := newLoginTest(t, auth.SignupPolicy{Mode: auth.SignupOpen})
+
+	res, err := lt.login(t, authtest.Claims(testClientID, "google-1", "new@example.com"))
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	user, err := lt.queries.FindUserByGoogleID(t.Context(), sql.NullString{String: "google-1", Valid: true})
+	if err != nil {
+		t.Fatalf("find created user: %v", err)
+	}
+	if user.Email != "new@example.com" || user.Name != "Test User" {
+		t.Errorf("created user = %q %q, want new@example.com Test User", user.Email, user.Name)
+	}
+	if res.GetUser().GetId() != user.ID {
+		t.Errorf("response user ID = %d, want %d", res.GetUser().GetId(), user.ID)
+	}
+
+	claims, err := lt.authService.ValidateJWT(res.GetJwt())
+	if err != nil {
+		t.Fatalf("issued JWT is invalid: %v", err)
+	}
+	if claims.UserID != user.ID || claims.Scopes != nil {
+		t.Errorf("JWT claims = user %d scopes %v, want user %d without scopes", claims.UserID, claims.Scopes, user.ID)
+	}
+}
+
+func TestLoginUpdatesProfile(t *testing.T) {
+	lt := newLoginTest(t, auth.SignupPolicy{Mode: auth.SignupOpen})
+
+	first, err := lt.login(t, authtest.Claims(testClientID, "google-1", "user@example.com"))
+	if err != nil {
+		t.Fatalf("first Login: %v", err)
+	}
+
+	claims := authtest.Claims(testClientID, "google-1", "user@example.com")
+	claims["name"] = "Renamed User"
+	second, err := lt.login(t, claims)
+	if err != nil {
+		t.Fatalf("second Login: %v", err)
+	}
+
+	if second.GetUser().GetId() != first.GetUser().GetId() {
+		t.Errorf("second login returned user %d, want the existing user %d", second.GetUser().GetId(), first.GetUser().GetId())
+	}
+	if got := second.GetUser().GetName(); got != "Renamed User" {
+		t.Errorf("response name = %q, want Renamed User", got)
+	}
+	user, err := lt.queries.GetUser(t.Context(), first.GetUser().GetId())
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user.Name != "Renamed User" {
+		t.Errorf("stored name = %q, want Renamed User", user.Name)
+	}
+}
+
+func TestLoginRejectsUnverifiedEmail(t *testing.T) {
+	lt := newLoginTest(t, auth.SignupPolicy{Mode: auth.SignupOpen})
+
+	claims := authtest.Claims(testClientID, "google-1", "user@example.com")
+	claims["email_verified"] = false
+	_, err := lt.login(t, claims)
+	assertAPIError(t, err, connect.CodePermissionDenied, auth.ReasonEmailNotVerified)
+
+	if _, err := lt.queries.FindUserByGoogleID(t.Context(), sql.NullString{String: "google-1", Valid: true}); err != sql.ErrNoRows {
+		t.Errorf("find user after rejected login: %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestLoginRejectsForeignTokens(t *testing.T) {
+	lt := newLoginTest(t, auth.SignupPolicy{Mode: auth.SignupOpen})
+
+	wrongAudience := authtest.Claims("another-client.apps.googleusercontent.com", "google-1", "user@example.com")
+	wrongIssuer := authtest.Claims(testClientID, "google-1", "user@example.com")
+	wrongIssuer["iss"] = "https://accounts.example.com"
+
+	for name, claims := range map[string]jwt.MapClaims{"wrong audience": wrongAudience, "wrong issuer": wrongIssuer} {
+		t.Run(name, func(t *testing.T) {
+			_, err := lt.login(t, claims)
+			assertAPIError(t, err, connect.CodeUnauthenticated, auth.ReasonInvalidToken)
+		})
+	}
+}
+
+func TestLoginInviteOnly(t *testing.T) {
+	lt := newLoginTest(t, auth.SignupPolicy{Mode: auth.SignupInviteOnly})
+	if err := auth.Invite(t.Context(), lt.queries, "Invited@Example.com"); err != nil {
+		t.Fatalf("Invite: %v", err)
+	}
+
+	_, err := lt.login(t, authtest.Claims(testClientID, "google-1", "stranger@example.com"))
+	assertAPIError(t, err, connect.CodePermissionDenied, auth.ReasonSignupRejected)
+
+	if _, err := lt.login(t, authtest.Claims(testClientID, "google-2", "invited@example.com")); err != nil {
+		t.Fatalf("Login with an invitation: %v", err)
+	}
+	if _, err := lt.queries.GetPendingInvitationByEmail(t.Context(), "invited@example.com"); err != sql.ErrNoRows {
+		t.Errorf("invitation is still pending after signup: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/damejeras/goose/db/sqlc"
+)
+
+var (
+	ErrSignupNotInvited        = errors.New("signup requires an invitation")
+	ErrEmailDomainNotAllowed   = errors.New("email domain is not allowed to sign up")
+	ErrHostedDomainNotAllowed  = errors.New("google workspace domain is not allowed to sign up")
+	ErrUnknownSignupPolicyMode = errors.New("unknown signup policy mode")
+)
+
+// SignupMode controls who may create a new account on first login
+type SignupMode string
+
+const (
+	// SignupOpen lets any verified Google account sign up
+	SignupOpen SignupMode = "open"
+	// SignupInviteOnly only lets emails with a pending invitation sign up
+	SignupInviteOnly SignupMode = "invite-only"
+	// SignupDomains only lets emails from allowed domains sign up
+	SignupDomains SignupMode = "domains"
+)
+
+// SignupPolicy decides whether a verified Google account may create a new user.
+// Existing users are not affected by the policy.
+type SignupPolicy struct {
+	Mode SignupMode
+	// AllowedDomains lists email domains accepted in SignupDomains mode
+	AllowedDomains []string
+	// AllowedHostedDomains lists Google Workspace "hd" claim values accepted in SignupDomains mode
+	AllowedHostedDomains []string
+}
+
+// Validate checks that the policy is complete for its mode
+func (p SignupPolicy) Validate() error {
+	switch p.Mode {
+	case SignupOpen, SignupInviteOnly:
+		return nil
+	case SignupDomains:
+		if len(p.AllowedDomains) == 0 && len(p.AllowedHostedDomains) == 0 {
+			return fmt.Errorf("signup mode %q requires allowed domains or hosted domains", p.Mode)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownSignupPolicyMode, p.Mode)
+	}
+}
+
+// Invite records a pending invitation for each email, e.g. the ones listed in
+// the config at startup. Emails that were already invited, including ones that
+// have since signed up, are left as they are.
+func Invite(ctx context.Context, queries *sqlc.Queries, emails ...string) error {
+	for _, email := range emails {
+		if err := queries.CreateInvitation(ctx, normalizeEmail(email)); err != nil {
+			return fmt.Errorf("invite %s: %w", email, err)
+		}
+	}
+	return nil
+}
+
+// checkDomains verifies the token against the allowed email and hosted domains.
+// When both lists are set, both must match.
+func (p SignupPolicy) checkDomains(tokenInfo *GoogleTokenInfo) error {
+	if len(p.AllowedDomains) > 0 && !containsFold(p.AllowedDomains, emailDomain(tokenInfo.Email)) {
+		return ErrEmailDomainNotAllowed
+	}
+	if len(p.AllowedHostedDomains) > 0 && !containsFold(p.AllowedHostedDomains, tokenInfo.HostedDomain) {
+		return ErrHostedDomainNotAllowed
+	}
+	return nil
+}
+
+// normalizeEmail returns the form invitations are stored under
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return email[at+1:]
+}
+
+func containsFold(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
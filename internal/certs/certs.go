@@ -0,0 +1,217 @@
+// Package certs terminates TLS with a certificate that is reloaded from disk
+// when its files change or the process receives SIGHUP, and optionally
+// verifies client certificates against a CA bundle.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ClientAuth decides whether clients must present a certificate
+type ClientAuth string
+
+const (
+	// ClientAuthOptional verifies client certificates when presented, so browsers still work
+	ClientAuthOptional ClientAuth = "optional"
+	// ClientAuthRequire rejects connections without a valid client certificate
+	ClientAuthRequire ClientAuth = "require"
+)
+
+// reloadDebounce coalesces the bursts of events produced by a single certificate rotation
+const reloadDebounce = 250 * time.Millisecond
+
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables client certificate verification against this PEM bundle
+	ClientCAFile string
+	ClientAuth   ClientAuth
+}
+
+// Validate checks that the files are set consistently
+func (c Config) Validate() error {
+	var errs []error
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		errs = append(errs, errors.New("cert_file and key_file must be set together"))
+	}
+	if c.ClientCAFile != "" && c.CertFile == "" {
+		errs = append(errs, errors.New("client_ca_file requires cert_file and key_file"))
+	}
+	switch c.ClientAuth {
+	case ClientAuthOptional, ClientAuthRequire:
+	default:
+		errs = append(errs, fmt.Errorf("unknown client_auth %q, use optional or require", c.ClientAuth))
+	}
+	return errors.Join(errs...)
+}
+
+// Enabled reports whether TLS is configured
+func (c Config) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Reloader holds the current TLS configuration and replaces it when the
+// certificate, key or client CA files change. A failed reload keeps serving
+// the previous certificate.
+type Reloader struct {
+	config  Config
+	logger  *slog.Logger
+	current atomic.Pointer[tls.Config]
+}
+
+// NewReloader loads the files once, failing if they are unusable
+func NewReloader(config Config, logger *slog.Logger) (*Reloader, error) {
+	r := &Reloader{config: config, logger: logger}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the configuration for http.Server; every handshake uses
+// the most recently loaded files
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+		// http.Server requires a certificate source before it serves TLS
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.current.Load().Certificates[0], nil
+		},
+	}
+}
+
+// Reload reads the files and swaps in the new configuration
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{cert},
+	}
+	if r.config.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("client CA bundle %s contains no certificates", r.config.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if r.config.ClientAuth == ClientAuthRequire {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	r.current.Store(config)
+	if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+		r.logger.Info("TLS certificate loaded", "subject", leaf.Subject.String(), "not_after", leaf.NotAfter)
+	}
+	return nil
+}
+
+// Watch reloads on SIGHUP and when the files change until ctx is cancelled.
+// The parent directories are watched, so files replaced by rename and
+// Kubernetes secret volumes, which swap a symlink, are picked up.
+func (r *Reloader) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events <-chan fsnotify.Event
+	var watchErrs <-chan error
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		r.logger.Error("failed to watch TLS files, reloading on SIGHUP only", "error", err)
+	} else {
+		defer watcher.Close()
+		for _, dir := range r.dirs() {
+			if err := watcher.Add(dir); err != nil {
+				r.logger.Error("failed to watch TLS directory", "dir", dir, "error", err)
+			}
+		}
+		events, watchErrs = watcher.Events, watcher.Errors
+	}
+
+	debounce := time.NewTimer(0)
+	<-debounce.C
+	for {
+		select {
+		case <-ctx.Done():
+			debounce.Stop()
+			return
+		case <-hup:
+			r.reload("SIGHUP")
+		case event := <-events:
+			if event.Has(fsnotify.Chmod) || !r.watches(event.Name) {
+				continue
+			}
+			debounce.Reset(reloadDebounce)
+		case <-debounce.C:
+			r.reload("file change")
+		case err := <-watchErrs:
+			r.logger.Warn("TLS file watcher error", "error", err)
+		}
+	}
+}
+
+func (r *Reloader) reload(trigger string) {
+	if err := r.Reload(); err != nil {
+		r.logger.Error("failed to reload TLS certificate, keeping the previous one", "trigger", trigger, "error", err)
+	}
+}
+
+// watches reports whether a change to name may affect the configured files.
+// Kubernetes updates secret volumes through "..data" symlinks.
+func (r *Reloader) watches(name string) bool {
+	base := filepath.Base(name)
+	if strings.HasPrefix(base, "..") {
+		return true
+	}
+	for _, file := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile} {
+		if file != "" && filepath.Base(file) == base {
+			return true
+		}
+	}
+	return false
+}
+
+// dirs returns the distinct directories holding the configured files
+func (r *Reloader) dirs() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, file := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+		dir := filepath.Dir(file)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
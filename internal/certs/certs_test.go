@@ -0,0 +1,255 @@
+package certs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/damejeras/goose/internal/certs"
+)
+
+// logs collects log lines written by the reloader's watcher goroutine
+type logs struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *logs) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *logs) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
+
+// writeCert writes a self-signed certificate for commonName and its key,
+// replacing the files by rename as certificate managers do
+func writeCert(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	replace(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	replace(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+func replace(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("rename %s: %v", tmp, err)
+	}
+}
+
+// served returns the common name of the certificate new handshakes get
+func served(t *testing.T, r *certs.Reloader) string {
+	t.Helper()
+
+	config, err := r.TLSConfig().GetConfigForClient(nil)
+	if err != nil {
+		t.Fatalf("GetConfigForClient: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+// eventually polls cond until it holds or a few seconds pass
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// newReloader loads a certificate for "first" and returns the reloader with its config and logs
+func newReloader(t *testing.T) (*certs.Reloader, certs.Config, *logs) {
+	t.Helper()
+
+	dir := t.TempDir()
+	config := certs.Config{
+		CertFile:   filepath.Join(dir, "tls.crt"),
+		KeyFile:    filepath.Join(dir, "tls.key"),
+		ClientAuth: certs.ClientAuthOptional,
+	}
+	writeCert(t, config.CertFile, config.KeyFile, "first")
+
+	l := &logs{}
+	r, err := certs.NewReloader(config, slog.New(slog.NewTextHandler(l, nil)))
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	if got := served(t, r); got != "first" {
+		t.Fatalf("serving %q, want first", got)
+	}
+	return r, config, l
+}
+
+// watch runs r.Watch until the test ends
+func watch(t *testing.T, r *certs.Reloader) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Watch(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config certs.Config
+		valid  bool
+	}{
+		{name: "disabled", config: certs.Config{ClientAuth: certs.ClientAuthOptional}, valid: true},
+		{name: "certificate", config: certs.Config{CertFile: "tls.crt", KeyFile: "tls.key", ClientAuth: certs.ClientAuthOptional}, valid: true},
+		{name: "client CA", config: certs.Config{CertFile: "tls.crt", KeyFile: "tls.key", ClientCAFile: "ca.crt", ClientAuth: certs.ClientAuthRequire}, valid: true},
+		{name: "certificate without key", config: certs.Config{CertFile: "tls.crt", ClientAuth: certs.ClientAuthOptional}},
+		{name: "client CA without certificate", config: certs.Config{ClientCAFile: "ca.crt", ClientAuth: certs.ClientAuthOptional}},
+		{name: "unknown client auth", config: certs.Config{ClientAuth: "sometimes"}},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate = %v, want valid %t", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestNewReloaderBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	config := certs.Config{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	if _, err := certs.NewReloader(config, slog.New(slog.DiscardHandler)); err == nil {
+		t.Error("NewReloader succeeded without files")
+	}
+
+	writeCert(t, config.CertFile, config.KeyFile, "first")
+	config.ClientCAFile = filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(config.ClientCAFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := certs.NewReloader(config, slog.New(slog.DiscardHandler)); err == nil || !strings.Contains(err.Error(), "contains no certificates") {
+		t.Errorf("NewReloader with an empty CA bundle = %v", err)
+	}
+}
+
+func TestReloadKeepsPreviousOnError(t *testing.T) {
+	r, config, _ := newReloader(t)
+
+	writeCert(t, config.CertFile, config.KeyFile, "second")
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := served(t, r); got != "second" {
+		t.Fatalf("serving %q after reload, want second", got)
+	}
+
+	replace(t, config.CertFile, []byte("not a certificate"))
+	if err := r.Reload(); err == nil {
+		t.Fatal("Reload of a bad certificate succeeded")
+	}
+	if got := served(t, r); got != "second" {
+		t.Errorf("serving %q after a failed reload, want second", got)
+	}
+}
+
+func TestWatchFileChange(t *testing.T) {
+	r, config, l := newReloader(t)
+	watch(t, r)
+
+	// the watcher starts asynchronously; keep rotating until a change is seen
+	eventually(t, "the rotated certificate", func() bool {
+		writeCert(t, config.CertFile, config.KeyFile, "second")
+		time.Sleep(400 * time.Millisecond)
+		return served(t, r) == "second"
+	})
+
+	replace(t, config.CertFile, []byte("not a certificate"))
+	eventually(t, "the failed reload to be logged", func() bool {
+		return strings.Contains(l.String(), `trigger="file change"`)
+	})
+	if got := served(t, r); got != "second" {
+		t.Errorf("serving %q after a bad certificate was written, want second", got)
+	}
+}
+
+func TestWatchSIGHUP(t *testing.T) {
+	// keep a stray SIGHUP from killing the test binary before Watch subscribes
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	r, config, l := newReloader(t)
+	replace(t, config.CertFile, []byte("not a certificate"))
+	watch(t, r)
+
+	eventually(t, "the SIGHUP reload to be logged", func() bool {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatalf("send SIGHUP: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		return strings.Contains(l.String(), "trigger=SIGHUP")
+	})
+	if got := served(t, r); got != "first" {
+		t.Errorf("serving %q after a failed SIGHUP reload, want first", got)
+	}
+
+	writeCert(t, config.CertFile, config.KeyFile, "second")
+	eventually(t, "the certificate reloaded", func() bool {
+		syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		time.Sleep(50 * time.Millisecond)
+		return served(t, r) == "second"
+	})
+}
@@ -0,0 +1,504 @@
+// Package config loads the server configuration from an optional YAML or TOML
+// file, GOOSE_* environment variables and command line flags, in that order of
+// precedence (flags win), and validates the result up front.
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/certs"
+	"github.com/damejeras/goose/internal/deviceauth"
+	"github.com/damejeras/goose/internal/headers"
+	"github.com/damejeras/goose/internal/listener"
+	"github.com/damejeras/goose/internal/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes every environment variable read by Load
+const EnvPrefix = "GOOSE_"
+
+// Config is the complete server configuration
+type Config struct {
+	Server      ServerConfig      `yaml:"server" toml:"server"`
+	TLS         TLSConfig         `yaml:"tls" toml:"tls"`
+	Admin       AdminConfig       `yaml:"admin" toml:"admin"`
+	CORS        CORSConfig        `yaml:"cors" toml:"cors"`
+	Security    SecurityConfig    `yaml:"security_headers" toml:"security_headers"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database"`
+	Log         LogConfig         `yaml:"log" toml:"log"`
+	Auth        AuthConfig        `yaml:"auth" toml:"auth"`
+	Reflection  ReflectionConfig  `yaml:"reflection" toml:"reflection"`
+	Docs        DocsConfig        `yaml:"docs" toml:"docs"`
+	Metrics     MetricsConfig     `yaml:"metrics" toml:"metrics"`
+	Tracing     TracingConfig     `yaml:"tracing" toml:"tracing"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" toml:"idempotency"`
+	Dev         DevConfig         `yaml:"dev" toml:"dev"`
+}
+
+type ServerConfig struct {
+	Port string `yaml:"port" toml:"port"`
+	// Listen replaces Port with one or more addresses: host:port, unix:/path,
+	// systemd or systemd:NAME for sockets passed by systemd socket activation
+	Listen []string `yaml:"listen" toml:"listen"`
+	// ShutdownDelay is how long the server keeps serving after reporting not ready
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	// DrainTimeout bounds how long shutdown waits for in-flight requests
+	DrainTimeout time.Duration `yaml:"drain_timeout" toml:"drain_timeout"`
+}
+
+type TLSConfig struct {
+	// CertFile and KeyFile enable TLS; they are reloaded when they change and on SIGHUP
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+	// ClientCAFile verifies client certificates against this PEM bundle
+	ClientCAFile string `yaml:"client_ca_file" toml:"client_ca_file"`
+	// ClientAuth is optional or require
+	ClientAuth string `yaml:"client_auth" toml:"client_auth"`
+	// ClientPrincipals maps client certificate common names to the email of the
+	// user a service acts as, written name=email
+	ClientPrincipals []string `yaml:"client_principals" toml:"client_principals"`
+}
+
+type AdminConfig struct {
+	// Listen serves metrics, pprof, health checks and reflection on a separate
+	// address, e.g. "127.0.0.1:9090" or "unix:/run/goose/admin.sock", instead
+	// of the main port. pprof is only served here.
+	Listen string `yaml:"listen" toml:"listen"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins may call the API from browsers, e.g. "https://app.example.com"
+	// or "https://*.example.com"; empty disables CORS
+	AllowedOrigins   []string      `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" toml:"max_age"`
+}
+
+type SecurityConfig struct {
+	// HSTSMaxAge is sent in Strict-Transport-Security on HTTPS responses; 0s disables it
+	HSTSMaxAge time.Duration `yaml:"hsts_max_age" toml:"hsts_max_age"`
+	// HSTSIncludeSubdomains extends HSTS to every subdomain
+	HSTSIncludeSubdomains bool   `yaml:"hsts_include_subdomains" toml:"hsts_include_subdomains"`
+	FrameOptions          string `yaml:"frame_options" toml:"frame_options"`
+	ReferrerPolicy        string `yaml:"referrer_policy" toml:"referrer_policy"`
+}
+
+type DatabaseConfig struct {
+	Path string `yaml:"path" toml:"path"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" toml:"level"`
+}
+
+type AuthConfig struct {
+	GoogleClientID string `yaml:"google_client_id" toml:"google_client_id"`
+	// JWTSecret is base64 encoded; when empty a random secret is generated at startup
+	JWTSecret     string        `yaml:"jwt_secret" toml:"jwt_secret"`
+	JWTExpiration time.Duration `yaml:"jwt_expiration" toml:"jwt_expiration"`
+	Signup        SignupConfig  `yaml:"signup" toml:"signup"`
+	Cookies       CookiesConfig `yaml:"cookies" toml:"cookies"`
+	// AdminEmails may call admin-only endpoints such as reflection
+	AdminEmails []string     `yaml:"admin_emails" toml:"admin_emails"`
+	Device      DeviceConfig `yaml:"device" toml:"device"`
+}
+
+type SignupConfig struct {
+	Policy               string   `yaml:"policy" toml:"policy"`
+	AllowedDomains       []string `yaml:"allowed_domains" toml:"allowed_domains"`
+	AllowedHostedDomains []string `yaml:"allowed_hosted_domains" toml:"allowed_hosted_domains"`
+	// Invitations are emails invited at startup, for the invite-only policy
+	Invitations []string `yaml:"invitations" toml:"invitations"`
+}
+
+type DeviceConfig struct {
+	// VerificationURL is where users approve device logins, e.g.
+	// https://goose.example.com/device; empty uses /device on the host the login was started on
+	VerificationURL string `yaml:"verification_url" toml:"verification_url"`
+	// CodeTTL is how long the user has to approve a device login
+	CodeTTL time.Duration `yaml:"code_ttl" toml:"code_ttl"`
+	// PollInterval is the minimum time between polls of a device
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval"`
+	// MaxFailedAttempts is how many unknown user codes a user may enter within CodeTTL
+	MaxFailedAttempts int `yaml:"max_failed_attempts" toml:"max_failed_attempts"`
+	// MaxPending caps the device logins waiting for approval at once
+	MaxPending int `yaml:"max_pending" toml:"max_pending"`
+}
+
+type CookiesConfig struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled"`
+	Domain  string `yaml:"domain" toml:"domain"`
+}
+
+type ReflectionConfig struct {
+	// Enabled serves gRPC server reflection (v1 and v1alpha)
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+	// RequireAdmin restricts reflection to callers authenticated as an admin
+	RequireAdmin bool `yaml:"require_admin" toml:"require_admin"`
+}
+
+type DocsConfig struct {
+	// Enabled serves the OpenAPI document at /openapi.json and API docs at /docs
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+}
+
+type MetricsConfig struct {
+	// Enabled serves Prometheus metrics at /metrics
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout, otlp-http or otlp-grpc; stdout writes spans to stderr
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// Endpoint is the OTLP collector host:port; empty uses OTEL_EXPORTER_OTLP_ENDPOINT
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+	// TrustRemote continues traces started by callers instead of only linking to them
+	TrustRemote bool `yaml:"trust_remote" toml:"trust_remote"`
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long a response can be replayed for a retried Idempotency-Key
+	TTL time.Duration `yaml:"ttl" toml:"ttl"`
+	// LockTimeout is how long a key stays reserved by a request that never finished
+	LockTimeout time.Duration `yaml:"lock_timeout" toml:"lock_timeout"`
+}
+
+type DevConfig struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled"`
+	ViteURL string `yaml:"vite_url" toml:"vite_url"`
+}
+
+// Default returns the configuration used when nothing overrides it
+func Default() Config {
+	return Config{
+		Server: ServerConfig{Port: "8080", DrainTimeout: 30 * time.Second},
+		TLS:    TLSConfig{ClientAuth: string(certs.ClientAuthOptional)},
+		CORS:   CORSConfig{MaxAge: 2 * time.Hour},
+		Security: SecurityConfig{
+			HSTSMaxAge:     365 * 24 * time.Hour,
+			FrameOptions:   headers.FrameOptionsDeny,
+			ReferrerPolicy: "strict-origin-when-cross-origin",
+		},
+		Database: DatabaseConfig{Path: "storage/goose.db"},
+		Log:      LogConfig{Level: "info"},
+		Auth: AuthConfig{
+			JWTExpiration: 24 * time.Hour,
+			Signup:        SignupConfig{Policy: string(auth.SignupOpen)},
+			Device:        DeviceConfig{CodeTTL: 10 * time.Minute, PollInterval: 5 * time.Second, MaxFailedAttempts: 5, MaxPending: 1000},
+		},
+		Docs: DocsConfig{Enabled: true},
+		Tracing: TracingConfig{
+			Exporter:    string(tracing.ExporterNone),
+			SampleRatio: 1,
+			TrustRemote: true,
+		},
+		Idempotency: IdempotencyConfig{TTL: 24 * time.Hour, LockTimeout: time.Minute},
+		Dev:         DevConfig{ViteURL: "http://localhost:5173"},
+	}
+}
+
+// Options are the command line switches that control loading rather than the config itself
+type Options struct {
+	// PrintConfig asks the caller to print the effective redacted config and exit
+	PrintConfig bool
+	// Warnings are problems that don't stop the server, such as deprecated
+	// environment variables, for the caller to log
+	Warnings []string
+}
+
+// Load builds the configuration from defaults, the config file named by --config
+// or GOOSE_CONFIG, GOOSE_* environment variables and command line flags.
+// GOOGLE_CLIENT_ID and JWT_SECRET are still read when their GOOSE_AUTH_*
+// replacements are unset, with a warning in Options. All validation problems
+// are reported together.
+func Load(name string, args []string) (Config, Options, error) {
+	cfg := Default()
+	var opts Options
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(EnvPrefix+"CONFIG"), "Path to a YAML or TOML config file")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "Print the effective config with secrets redacted and exit")
+	flagValues := make(map[string]*rawValue)
+	for _, s := range settings(&cfg) {
+		v := &rawValue{isBool: s.isBool, repeated: s.repeated}
+		flagValues[s.flag] = v
+		fs.Var(v, s.flag, s.usage+" (env "+s.env()+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, opts, err
+	}
+
+	if *configPath != "" {
+		if err := loadFile(*configPath, &cfg); err != nil {
+			return cfg, opts, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings(&cfg) {
+		env := s.env()
+		value, ok := os.LookupEnv(env)
+		if !ok && s.legacyEnv != "" {
+			if value, ok = os.LookupEnv(s.legacyEnv); ok {
+				env = s.legacyEnv
+				opts.Warnings = append(opts.Warnings, fmt.Sprintf("%s is deprecated, use %s", s.legacyEnv, s.env()))
+			}
+		}
+		if ok {
+			if err := s.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", env, err))
+			}
+		}
+	}
+	for _, s := range settings(&cfg) {
+		if v := flagValues[s.flag]; v.set {
+			if err := s.set(v.value); err != nil {
+				errs = append(errs, fmt.Errorf("--%s: %w", s.flag, err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return cfg, opts, err
+	}
+
+	return cfg, opts, cfg.Validate()
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && err != io.EOF {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("parse config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parse config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Auth.GoogleClientID == "" {
+		errs = append(errs, errors.New("auth.google_client_id is required"))
+	}
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %q is not a valid port", c.Server.Port))
+	}
+	for _, address := range c.Server.Listen {
+		if err := listener.Validate(address); err != nil {
+			errs = append(errs, fmt.Errorf("server.listen %q is not a valid address: %w", address, err))
+		}
+	}
+	if c.Server.ShutdownDelay < 0 {
+		errs = append(errs, fmt.Errorf("server.shutdown_delay must not be negative, got %s", c.Server.ShutdownDelay))
+	}
+	if c.Server.DrainTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("server.drain_timeout must be positive, got %s", c.Server.DrainTimeout))
+	}
+	if err := c.CertsConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tls: %w", err))
+	}
+	if len(c.TLS.ClientPrincipals) > 0 && c.TLS.ClientCAFile == "" {
+		errs = append(errs, errors.New("tls.client_principals needs tls.client_ca_file"))
+	}
+	if _, err := c.ServicePrincipals(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.CORSConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("cors: %w", err))
+	}
+	if err := c.SecurityHeadersConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("security_headers: %w", err))
+	}
+	if c.Database.Path == "" {
+		errs = append(errs, errors.New("database.path is required"))
+	}
+	if _, err := c.Log.SlogLevel(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Auth.JWTSecret != "" {
+		if _, err := base64.StdEncoding.DecodeString(c.Auth.JWTSecret); err != nil {
+			errs = append(errs, fmt.Errorf("auth.jwt_secret is not valid base64: %w", err))
+		}
+	}
+	if c.Auth.JWTExpiration <= 0 {
+		errs = append(errs, fmt.Errorf("auth.jwt_expiration must be positive, got %s", c.Auth.JWTExpiration))
+	}
+	if err := c.SignupPolicy().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("auth.signup: %w", err))
+	}
+	for _, email := range c.Auth.Signup.Invitations {
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			errs = append(errs, fmt.Errorf("auth.signup.invitations %q is not an email address", email))
+		}
+	}
+	if c.Reflection.RequireAdmin && len(c.Auth.AdminEmails) == 0 {
+		errs = append(errs, errors.New("reflection.require_admin needs at least one auth.admin_emails entry"))
+	}
+	if c.Admin.Listen != "" {
+		if err := listener.Validate(c.Admin.Listen); err != nil {
+			errs = append(errs, fmt.Errorf("admin.listen %q is not a valid address: %w", c.Admin.Listen, err))
+		}
+	}
+	if err := c.DeviceAuthConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("auth.device: %w", err))
+	}
+	if err := c.TracingConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tracing: %w", err))
+	}
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, fmt.Errorf("idempotency.ttl must be positive, got %s", c.Idempotency.TTL))
+	}
+	if c.Idempotency.LockTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("idempotency.lock_timeout must be positive, got %s", c.Idempotency.LockTimeout))
+	}
+	if c.Dev.Enabled {
+		if u, err := url.Parse(c.Dev.ViteURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("dev.vite_url %q is not a valid URL", c.Dev.ViteURL))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SignupPolicy converts the signup section to the auth package's policy
+func (c Config) SignupPolicy() auth.SignupPolicy {
+	return auth.SignupPolicy{
+		Mode:                 auth.SignupMode(c.Auth.Signup.Policy),
+		AllowedDomains:       c.Auth.Signup.AllowedDomains,
+		AllowedHostedDomains: c.Auth.Signup.AllowedHostedDomains,
+	}
+}
+
+// DeviceAuthConfig converts the auth.device section to the deviceauth package's config
+func (c Config) DeviceAuthConfig() deviceauth.Config {
+	return deviceauth.Config{
+		VerificationURL:   c.Auth.Device.VerificationURL,
+		CodeTTL:           c.Auth.Device.CodeTTL,
+		PollInterval:      c.Auth.Device.PollInterval,
+		MaxFailedAttempts: c.Auth.Device.MaxFailedAttempts,
+		MaxPending:        c.Auth.Device.MaxPending,
+	}
+}
+
+// ListenAddresses returns the addresses of the public server
+func (c Config) ListenAddresses() []string {
+	if len(c.Server.Listen) > 0 {
+		return c.Server.Listen
+	}
+	return []string{":" + c.Server.Port}
+}
+
+// CertsConfig converts the tls section to the certs package's config
+func (c Config) CertsConfig() certs.Config {
+	return certs.Config{
+		CertFile:     c.TLS.CertFile,
+		KeyFile:      c.TLS.KeyFile,
+		ClientCAFile: c.TLS.ClientCAFile,
+		ClientAuth:   certs.ClientAuth(c.TLS.ClientAuth),
+	}
+}
+
+// ServicePrincipals parses tls.client_principals into a common name to email map
+func (c Config) ServicePrincipals() (map[string]string, error) {
+	principals := make(map[string]string, len(c.TLS.ClientPrincipals))
+	for _, entry := range c.TLS.ClientPrincipals {
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 || i == len(entry)-1 {
+			return nil, fmt.Errorf("tls.client_principals entry %q must be name=email", entry)
+		}
+		principals[entry[:i]] = entry[i+1:]
+	}
+	return principals, nil
+}
+
+// CORSConfig converts the cors section to the headers package's config
+func (c Config) CORSConfig() headers.CORSConfig {
+	return headers.CORSConfig{
+		AllowedOrigins:   c.CORS.AllowedOrigins,
+		AllowCredentials: c.CORS.AllowCredentials,
+		MaxAge:           c.CORS.MaxAge,
+	}
+}
+
+// SecurityHeadersConfig converts the security_headers section to the headers package's config
+func (c Config) SecurityHeadersConfig() headers.SecurityConfig {
+	return headers.SecurityConfig{
+		HSTSMaxAge:            c.Security.HSTSMaxAge,
+		HSTSIncludeSubdomains: c.Security.HSTSIncludeSubdomains,
+		FrameOptions:          c.Security.FrameOptions,
+		ReferrerPolicy:        c.Security.ReferrerPolicy,
+	}
+}
+
+// TracingConfig converts the tracing section to the tracing package's config
+func (c Config) TracingConfig() tracing.Config {
+	return tracing.Config{
+		Exporter:    tracing.Exporter(c.Tracing.Exporter),
+		Endpoint:    c.Tracing.Endpoint,
+		Insecure:    c.Tracing.Insecure,
+		SampleRatio: c.Tracing.SampleRatio,
+		TrustRemote: c.Tracing.TrustRemote,
+	}
+}
+
+// SlogLevel parses the configured log level
+func (l LogConfig) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return level, fmt.Errorf("log.level %q is not a valid level", l.Level)
+	}
+	return level, nil
+}
+
+// Redacted returns a copy of the config that is safe to print or log
+func (c Config) Redacted() Config {
+	if c.Auth.JWTSecret != "" {
+		c.Auth.JWTSecret = "[REDACTED]"
+	}
+	return c
+}
+
+// Print writes the effective redacted config as YAML
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
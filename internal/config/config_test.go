@@ -0,0 +1,225 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damejeras/goose/internal/config"
+)
+
+const testSecret = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "goose.yaml", `
+server:
+  port: "1000"
+database:
+  path: /var/lib/goose/file.db
+log:
+  level: debug
+auth:
+  google_client_id: file-client
+  jwt_expiration: 1h
+`)
+	t.Setenv("GOOSE_SERVER_PORT", "2000")
+	t.Setenv("GOOSE_DATABASE_PATH", "/var/lib/goose/env.db")
+
+	cfg, _, err := config.Load("goose", []string{"--config", path, "--port", "3000"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{name: "flag over env and file", got: cfg.Server.Port, want: "3000"},
+		{name: "env over file", got: cfg.Database.Path, want: "/var/lib/goose/env.db"},
+		{name: "file over default", got: cfg.Log.Level, want: "debug"},
+		{name: "file duration", got: cfg.Auth.JWTExpiration, want: time.Hour},
+		{name: "default", got: cfg.Idempotency.TTL, want: 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "goose.toml", `
+[auth]
+google_client_id = "toml-client"
+`)
+	cfg, _, err := config.Load("goose", []string{"--config", path})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Auth.GoogleClientID != "toml-client" {
+		t.Errorf("google_client_id = %q, want toml-client", cfg.Auth.GoogleClientID)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	for name, content := range map[string]string{
+		"goose.yaml": "auth:\n  google_client_id: x\n  jwt_secrets: typo\n",
+		"goose.toml": "[auth]\ngoogle_client_id = \"x\"\njwt_secrets = \"typo\"\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := config.Load("goose", []string{"--config", writeFile(t, name, content)}); err == nil {
+				t.Fatal("Load succeeded with an unknown key")
+			}
+		})
+	}
+}
+
+func TestLoadLists(t *testing.T) {
+	t.Setenv("GOOSE_CORS_ALLOWED_ORIGINS", "https://a.example.com, https://b.example.com,,")
+
+	cfg, _, err := config.Load("goose", []string{
+		"--google-client-id", "x",
+		"--listen", "127.0.0.1:8080",
+		"--listen", "unix:/run/goose.sock",
+		"--admin-emails", "a@example.com",
+		"--admin-emails", "b@example.com",
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if want := []string{"127.0.0.1:8080", "unix:/run/goose.sock"}; !slices.Equal(cfg.Server.Listen, want) {
+		t.Errorf("repeated --listen = %v, want %v", cfg.Server.Listen, want)
+	}
+	// flags that aren't repeatable take their last value
+	if want := []string{"b@example.com"}; !slices.Equal(cfg.Auth.AdminEmails, want) {
+		t.Errorf("--admin-emails given twice = %v, want %v", cfg.Auth.AdminEmails, want)
+	}
+	if want := []string{"https://a.example.com", "https://b.example.com"}; !slices.Equal(cfg.CORS.AllowedOrigins, want) {
+		t.Errorf("comma-separated env = %v, want %v", cfg.CORS.AllowedOrigins, want)
+	}
+}
+
+func TestLoadLegacyEnvironment(t *testing.T) {
+	t.Setenv("GOOGLE_CLIENT_ID", "legacy-client")
+	t.Setenv("JWT_SECRET", testSecret)
+
+	cfg, opts, err := config.Load("goose", nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Auth.GoogleClientID != "legacy-client" || cfg.Auth.JWTSecret != testSecret {
+		t.Errorf("legacy variables were ignored: client %q secret %q", cfg.Auth.GoogleClientID, cfg.Auth.JWTSecret)
+	}
+	if len(opts.Warnings) != 2 || !strings.Contains(opts.Warnings[0], "GOOSE_AUTH_GOOGLE_CLIENT_ID") {
+		t.Errorf("warnings = %q, want one per legacy variable naming its replacement", opts.Warnings)
+	}
+
+	// the new name wins without a warning
+	t.Setenv("GOOSE_AUTH_GOOGLE_CLIENT_ID", "new-client")
+	cfg, opts, err = config.Load("goose", nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Auth.GoogleClientID != "new-client" {
+		t.Errorf("google_client_id = %q, want new-client", cfg.Auth.GoogleClientID)
+	}
+	if len(opts.Warnings) != 1 || !strings.HasPrefix(opts.Warnings[0], "JWT_SECRET") {
+		t.Errorf("warnings = %q, want only JWT_SECRET", opts.Warnings)
+	}
+}
+
+func TestLoadReportsEveryError(t *testing.T) {
+	_, _, err := config.Load("goose", []string{
+		"--port", "http",
+		"--log-level", "loud",
+		"--jwt-secret", "not base64!",
+		"--idempotency-ttl", "0s",
+	})
+	if err == nil {
+		t.Fatal("Load succeeded with an invalid config")
+	}
+	for _, want := range []string{
+		"auth.google_client_id is required",
+		`server.port "http" is not a valid port`,
+		"loud",
+		"auth.jwt_secret is not valid base64",
+		"idempotency.ttl must be positive",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %q", err, want)
+		}
+	}
+
+	if _, _, err := config.Load("goose", []string{"--google-client-id", "x", "--drain-timeout", "soon"}); err == nil || !strings.Contains(err.Error(), "--drain-timeout") {
+		t.Errorf("unparsable flag: %v, want an error naming --drain-timeout", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := config.Default()
+	valid.Auth.GoogleClientID = "x"
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate default config: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*config.Config)
+		want   string
+	}{
+		{name: "principals without CA", modify: func(c *config.Config) { c.TLS.ClientPrincipals = []string{"ci=ci@example.com"} }, want: "tls.client_principals needs tls.client_ca_file"},
+		{name: "invitation", modify: func(c *config.Config) { c.Auth.Signup.Invitations = []string{"not an email"} }, want: "is not an email address"},
+		{name: "reflection admin", modify: func(c *config.Config) { c.Reflection.RequireAdmin = true }, want: "reflection.require_admin needs"},
+		{name: "device attempts", modify: func(c *config.Config) { c.Auth.Device.MaxFailedAttempts = 0 }, want: "max_failed_attempts"},
+		{name: "dev URL", modify: func(c *config.Config) { c.Dev.Enabled, c.Dev.ViteURL = true, "localhost" }, want: "dev.vite_url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate: %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = testSecret
+
+	redacted := cfg.Redacted()
+	if redacted.Auth.JWTSecret != "[REDACTED]" {
+		t.Errorf("redacted secret = %q", redacted.Auth.JWTSecret)
+	}
+	if cfg.Auth.JWTSecret != testSecret {
+		t.Error("Redacted modified the original config")
+	}
+	if config.Default().Redacted().Auth.JWTSecret != "" {
+		t.Error("an unset secret is shown as redacted")
+	}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if strings.Contains(out.String(), testSecret) || !strings.Contains(out.String(), "[REDACTED]") {
+		t.Errorf("printed config exposes the secret:\n%s", out.String())
+	}
+}
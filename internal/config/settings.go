@@ -0,0 +1,172 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds one config field to its environment variable and command line flag
+type setting struct {
+	// key is the dotted path of the field in the config file, e.g. "auth.jwt_secret"
+	key    string
+	flag   string
+	usage  string
+	isBool bool
+	// repeated flags accumulate into a comma-separated list instead of overriding each other
+	repeated bool
+	// legacyEnv is the variable read before the GOOSE_ prefix, still honored with a warning
+	legacyEnv string
+	set       func(string) error
+}
+
+// env returns the environment variable for the setting, e.g. GOOSE_AUTH_JWT_SECRET
+func (s setting) env() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+// settings lists every field that can be overridden from the environment or flags
+func settings(c *Config) []setting {
+	return []setting{
+		{key: "server.port", flag: "port", usage: "Server port", set: setString(&c.Server.Port)},
+		{key: "server.listen", flag: "listen", usage: "Address to serve on instead of --port, repeatable: host:port, unix:/path, systemd or systemd:NAME", repeated: true, set: setList(&c.Server.Listen)},
+		{key: "server.shutdown_delay", flag: "shutdown-delay", usage: "How long to keep serving after reporting not ready on shutdown", set: setDuration(&c.Server.ShutdownDelay)},
+		{key: "server.drain_timeout", flag: "drain-timeout", usage: "How long to wait for in-flight requests on shutdown", set: setDuration(&c.Server.DrainTimeout)},
+		{key: "tls.cert_file", flag: "tls-cert", usage: "TLS certificate file; enables TLS together with --tls-key", set: setString(&c.TLS.CertFile)},
+		{key: "tls.key_file", flag: "tls-key", usage: "TLS private key file", set: setString(&c.TLS.KeyFile)},
+		{key: "tls.client_ca_file", flag: "tls-client-ca", usage: "CA bundle to verify client certificates against", set: setString(&c.TLS.ClientCAFile)},
+		{key: "tls.client_auth", flag: "tls-client-auth", usage: "Client certificates: optional or require", set: setString(&c.TLS.ClientAuth)},
+		{key: "tls.client_principals", flag: "tls-client-principals", usage: "Comma-separated name=email entries mapping client certificate common names to users", set: setList(&c.TLS.ClientPrincipals)},
+		{key: "admin.listen", flag: "admin-listen", usage: "Serve metrics, pprof, health checks and reflection on a separate address, e.g. 127.0.0.1:9090 or unix:/run/goose/admin.sock", set: setString(&c.Admin.Listen)},
+		{key: "cors.allowed_origins", flag: "cors-allowed-origins", usage: "Comma-separated origins allowed to call the API from browsers, e.g. https://app.example.com", set: setList(&c.CORS.AllowedOrigins)},
+		{key: "cors.allow_credentials", flag: "cors-allow-credentials", usage: "Let cross-origin requests carry the session cookie", isBool: true, set: setBool(&c.CORS.AllowCredentials)},
+		{key: "cors.max_age", flag: "cors-max-age", usage: "How long browsers may cache CORS preflight responses", set: setDuration(&c.CORS.MaxAge)},
+		{key: "security_headers.hsts_max_age", flag: "hsts-max-age", usage: "Strict-Transport-Security max-age on HTTPS responses, 0s disables it", set: setDuration(&c.Security.HSTSMaxAge)},
+		{key: "security_headers.hsts_include_subdomains", flag: "hsts-include-subdomains", usage: "Extend Strict-Transport-Security to every subdomain; only if all of them serve HTTPS", isBool: true, set: setBool(&c.Security.HSTSIncludeSubdomains)},
+		{key: "security_headers.frame_options", flag: "frame-options", usage: "X-Frame-Options: DENY, SAMEORIGIN or empty to allow framing", set: setString(&c.Security.FrameOptions)},
+		{key: "security_headers.referrer_policy", flag: "referrer-policy", usage: "Referrer-Policy header, empty disables it", set: setString(&c.Security.ReferrerPolicy)},
+		{key: "database.path", flag: "db", usage: "Database path", set: setString(&c.Database.Path)},
+		{key: "log.level", flag: "log-level", usage: "Log level: debug, info, warn or error", set: setString(&c.Log.Level)},
+		{key: "auth.google_client_id", flag: "google-client-id", usage: "Google OAuth client ID", legacyEnv: "GOOGLE_CLIENT_ID", set: setString(&c.Auth.GoogleClientID)},
+		{key: "auth.jwt_secret", flag: "jwt-secret", usage: "JWT secret (base64 encoded)", legacyEnv: "JWT_SECRET", set: setString(&c.Auth.JWTSecret)},
+		{key: "auth.jwt_expiration", flag: "jwt-expiration", usage: "JWT lifetime, e.g. 24h", set: setDuration(&c.Auth.JWTExpiration)},
+		{key: "auth.signup.policy", flag: "signup-policy", usage: "Signup policy: open, invite-only or domains", set: setString(&c.Auth.Signup.Policy)},
+		{key: "auth.signup.allowed_domains", flag: "signup-allowed-domains", usage: "Comma-separated email domains allowed to sign up in domains mode", set: setList(&c.Auth.Signup.AllowedDomains)},
+		{key: "auth.signup.allowed_hosted_domains", flag: "signup-allowed-hosted-domains", usage: "Comma-separated Google Workspace domains (hd claim) allowed to sign up in domains mode", set: setList(&c.Auth.Signup.AllowedHostedDomains)},
+		{key: "auth.signup.invitations", flag: "signup-invitations", usage: "Comma-separated emails to invite at startup for the invite-only policy", set: setList(&c.Auth.Signup.Invitations)},
+		{key: "auth.cookies.enabled", flag: "cookie-sessions", usage: "Carry browser sessions in HttpOnly cookies with CSRF protection", isBool: true, set: setBool(&c.Auth.Cookies.Enabled)},
+		{key: "auth.cookies.domain", flag: "cookie-domain", usage: "Domain attribute for session cookies (default host-only)", set: setString(&c.Auth.Cookies.Domain)},
+		{key: "auth.admin_emails", flag: "admin-emails", usage: "Comma-separated emails of administrators", set: setList(&c.Auth.AdminEmails)},
+		{key: "auth.device.verification_url", flag: "device-verification-url", usage: "Absolute URL of the page where users approve device logins, e.g. https://goose.example.com/device", set: setString(&c.Auth.Device.VerificationURL)},
+		{key: "auth.device.code_ttl", flag: "device-code-ttl", usage: "How long users have to approve a device login", set: setDuration(&c.Auth.Device.CodeTTL)},
+		{key: "auth.device.poll_interval", flag: "device-poll-interval", usage: "Minimum time between polls of a device login", set: setDuration(&c.Auth.Device.PollInterval)},
+		{key: "auth.device.max_failed_attempts", flag: "device-max-failed-attempts", usage: "How many unknown device codes a user may enter within the code TTL", set: setInt(&c.Auth.Device.MaxFailedAttempts)},
+		{key: "auth.device.max_pending", flag: "device-max-pending", usage: "How many device logins may wait for approval at once", set: setInt(&c.Auth.Device.MaxPending)},
+		{key: "reflection.enabled", flag: "reflection", usage: "Serve gRPC server reflection", isBool: true, set: setBool(&c.Reflection.Enabled)},
+		{key: "reflection.require_admin", flag: "reflection-require-admin", usage: "Require an admin credential for gRPC server reflection", isBool: true, set: setBool(&c.Reflection.RequireAdmin)},
+		{key: "docs.enabled", flag: "docs", usage: "Serve the OpenAPI document at /openapi.json and API docs at /docs", isBool: true, set: setBool(&c.Docs.Enabled)},
+		{key: "metrics.enabled", flag: "metrics", usage: "Serve Prometheus metrics at /metrics", isBool: true, set: setBool(&c.Metrics.Enabled)},
+		{key: "tracing.exporter", flag: "trace-exporter", usage: "Trace exporter: none, stdout (writes to stderr), otlp-http or otlp-grpc", set: setString(&c.Tracing.Exporter)},
+		{key: "tracing.endpoint", flag: "trace-endpoint", usage: "OTLP collector host:port (default from OTEL_EXPORTER_OTLP_ENDPOINT)", set: setString(&c.Tracing.Endpoint)},
+		{key: "tracing.insecure", flag: "trace-insecure", usage: "Connect to the OTLP collector without TLS", isBool: true, set: setBool(&c.Tracing.Insecure)},
+		{key: "tracing.sample_ratio", flag: "trace-sample-ratio", usage: "Fraction of new traces to record, 0 to 1", set: setFloat(&c.Tracing.SampleRatio)},
+		{key: "tracing.trust_remote", flag: "trace-trust-remote", usage: "Continue traces started by callers instead of only linking to them", isBool: true, set: setBool(&c.Tracing.TrustRemote)},
+		{key: "idempotency.ttl", flag: "idempotency-ttl", usage: "How long responses are replayed for a retried Idempotency-Key", set: setDuration(&c.Idempotency.TTL)},
+		{key: "idempotency.lock_timeout", flag: "idempotency-lock-timeout", usage: "How long an Idempotency-Key stays reserved by a request that never finished", set: setDuration(&c.Idempotency.LockTimeout)},
+		{key: "dev.enabled", flag: "dev", usage: "Enable development mode with Vite proxy", isBool: true, set: setBool(&c.Dev.Enabled)},
+		{key: "dev.vite_url", flag: "vite-url", usage: "Vite dev server URL", set: setString(&c.Dev.ViteURL)},
+	}
+}
+
+func setString(dst *string) func(string) error {
+	return func(value string) error {
+		*dst = value
+		return nil
+	}
+}
+
+func setBool(dst *bool) func(string) error {
+	return func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*dst = b
+		return nil
+	}
+}
+
+func setDuration(dst *time.Duration) func(string) error {
+	return func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*dst = d
+		return nil
+	}
+}
+
+func setInt(dst *int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*dst = n
+		return nil
+	}
+}
+
+func setFloat(dst *float64) func(string) error {
+	return func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*dst = f
+		return nil
+	}
+}
+
+// setList splits a comma-separated value, dropping empty entries
+func setList(dst *[]string) func(string) error {
+	return func(value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*dst = items
+		return nil
+	}
+}
+
+// rawValue records a flag's raw value so it can be applied after the config file and environment
+type rawValue struct {
+	value    string
+	set      bool
+	isBool   bool
+	repeated bool
+}
+
+func (v *rawValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *rawValue) Set(value string) error {
+	if v.repeated && v.set {
+		value = v.value + "," + value
+	}
+	v.value = value
+	v.set = true
+	return nil
+}
+
+func (v *rawValue) IsBoolFlag() bool {
+	return v.isBool
+}
@@ -0,0 +1,173 @@
+// Package deviceauth implements the OAuth 2.0 device authorization grant
+// (RFC 8628): a tool without a browser starts a login, the user approves it in
+// the web app and the tool polls until it receives a token
+package deviceauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAuthorizationPending = errors.New("the user has not approved the login yet")
+	ErrSlowDown             = errors.New("polling too often")
+	ErrAccessDenied         = errors.New("the user denied the login")
+	ErrExpiredToken         = errors.New("the device code has expired")
+	ErrInvalidDeviceCode    = errors.New("unknown device code")
+	ErrInvalidUserCode      = errors.New("unknown or expired user code")
+	ErrTooManyAttempts      = errors.New("too many unknown user codes")
+	ErrTooManyPending       = errors.New("too many device logins are pending, try again later")
+)
+
+// Reasons reported in the google.rpc.ErrorInfo of the errors above; the
+// first four are the error codes of RFC 8628
+const (
+	ReasonAuthorizationPending = "AUTHORIZATION_PENDING"
+	ReasonSlowDown             = "SLOW_DOWN"
+	ReasonAccessDenied         = "ACCESS_DENIED"
+	ReasonExpiredToken         = "EXPIRED_TOKEN"
+	ReasonInvalidDeviceCode    = "INVALID_DEVICE_CODE"
+	ReasonInvalidUserCode      = "INVALID_USER_CODE"
+	ReasonTooManyAttempts      = "TOO_MANY_ATTEMPTS"
+	ReasonTooManyPending       = "TOO_MANY_PENDING"
+)
+
+// Status of a device authorization
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusDenied   = "denied"
+)
+
+// slowDownIncrease is added to the interval of a client that polls too often
+const slowDownIncrease = 5 * time.Second
+
+type Config struct {
+	// VerificationURL is the absolute URL of the page where users enter the
+	// code, e.g. https://goose.example.com/device. Empty derives it from the
+	// scheme and host the login was started on; see BaseURLMiddleware.
+	VerificationURL string
+	// CodeTTL is how long the user has to approve a login
+	CodeTTL time.Duration
+	// PollInterval is the minimum time between polls
+	PollInterval time.Duration
+	// MaxFailedAttempts is how many unknown user codes a user may enter
+	// within CodeTTL, so guessing a live code is impractical
+	MaxFailedAttempts int
+	// MaxPending caps the unexpired logins nobody approved or denied yet.
+	// Starting a login needs no credentials, so this keeps anonymous callers
+	// from filling the database.
+	MaxPending int
+}
+
+// Validate checks the URL, durations and attempt limit
+func (c Config) Validate() error {
+	var errs []error
+	if c.VerificationURL != "" {
+		if u, err := url.Parse(c.VerificationURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("verification_url %q is not an absolute URL", c.VerificationURL))
+		}
+	}
+	if c.CodeTTL < time.Minute {
+		errs = append(errs, fmt.Errorf("code_ttl must be at least 1m, got %s", c.CodeTTL))
+	}
+	if c.PollInterval < time.Second {
+		errs = append(errs, fmt.Errorf("poll_interval must be at least 1s, got %s", c.PollInterval))
+	}
+	if c.MaxFailedAttempts < 1 {
+		errs = append(errs, fmt.Errorf("max_failed_attempts must be at least 1, got %d", c.MaxFailedAttempts))
+	}
+	if c.MaxPending < 1 {
+		errs = append(errs, fmt.Errorf("max_pending must be at least 1, got %d", c.MaxPending))
+	}
+	return errors.Join(errs...)
+}
+
+// verificationURI is VerificationURL, or the /device page of the server the
+// request came to. RFC 8628 requires an absolute URI, as clients show it to
+// users rather than resolve it.
+func (c Config) verificationURI(ctx context.Context) string {
+	if c.VerificationURL != "" {
+		return c.VerificationURL
+	}
+	base, _ := ctx.Value(baseURLKey{}).(string)
+	return base + "/device"
+}
+
+type baseURLKey struct{}
+
+// BaseURLMiddleware records the scheme and host of each request, from which
+// Start builds the verification URI when Config.VerificationURL is empty. A
+// TLS-terminating proxy must preserve the Host header and set X-Forwarded-Proto.
+func BaseURLMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		ctx := context.WithValue(r.Context(), baseURLKey{}, scheme+"://"+r.Host)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// userCodeAlphabet has no vowels, so codes don't spell words, and no
+// characters that are easy to confuse
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// userCodeLength gives 20^8, about 2^34, codes
+const userCodeLength = 8
+
+// generateDeviceCode returns a random device code
+func generateDeviceCode() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// generateUserCode returns a random user code in its normalized form, without separator
+func generateUserCode() (string, error) {
+	b := make([]byte, userCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := make([]byte, userCodeLength)
+	for i := range b {
+		// 256 is not a multiple of 20, but the bias doesn't help guessing a live code
+		code[i] = userCodeAlphabet[int(b[i])%len(userCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// formatUserCode splits a normalized user code in two halves, e.g. WDJB-MJHT
+func formatUserCode(code string) string {
+	return code[:len(code)/2] + "-" + code[len(code)/2:]
+}
+
+// normalizeUserCode uppercases code and drops separators and spaces users may type
+func normalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return -1
+	}, code)
+}
+
+// hash stores device codes like passwords, so a database leak doesn't hand out logins
+func hash(deviceCode string) []byte {
+	sum := sha256.Sum256([]byte(deviceCode))
+	return sum[:]
+}
@@ -0,0 +1,322 @@
+package deviceauth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/db/sqlc"
+	"github.com/damejeras/goose/internal/apierror"
+	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/logging"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Server implements the DeviceAuthService
+type Server struct {
+	authService *auth.Service
+	queries     *sqlc.Queries
+	logger      *slog.Logger
+	config      Config
+}
+
+// NewServer creates a new device authorization server
+func NewServer(authService *auth.Service, queries *sqlc.Queries, logger *slog.Logger, config Config) *Server {
+	return &Server{
+		authService: authService,
+		queries:     queries,
+		logger:      logger,
+		config:      config,
+	}
+}
+
+// Start issues a device code for the tool and a user code for the user
+func (s *Server) Start(ctx context.Context, req *connect.Request[v1.StartDeviceAuthRequest]) (*connect.Response[v1.StartDeviceAuthResponse], error) {
+	now := time.Now().UTC()
+	pending, err := s.queries.CountPendingDeviceAuthorizations(ctx, now)
+	if err != nil {
+		return nil, apierror.Internal("failed to count pending device authorizations", err)
+	}
+	if pending >= int64(s.config.MaxPending) {
+		s.log(ctx).WarnContext(ctx, "too many pending device logins", "pending", pending)
+		return nil, apierror.ResourceExhausted(ReasonTooManyPending, ErrTooManyPending)
+	}
+
+	deviceCode, err := generateDeviceCode()
+	if err != nil {
+		return nil, apierror.Internal("failed to generate device code", err)
+	}
+
+	var userCode string
+	// a new user code rarely collides with a live one; try another if it does
+	for attempt := 0; ; attempt++ {
+		userCode, err = generateUserCode()
+		if err != nil {
+			return nil, apierror.Internal("failed to generate user code", err)
+		}
+		err = s.queries.CreateDeviceAuthorization(ctx, sqlc.CreateDeviceAuthorizationParams{
+			DeviceCodeHash:  hash(deviceCode),
+			UserCode:        userCode,
+			ClientName:      req.Msg.ClientName,
+			IntervalSeconds: int64(s.config.PollInterval / time.Second),
+			CreatedAt:       now,
+			ExpiresAt:       now.Add(s.config.CodeTTL),
+		})
+		var sqliteErr sqlite3.Error
+		if attempt < 3 && errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			continue
+		}
+		if err != nil {
+			return nil, apierror.Internal("failed to store device authorization", err)
+		}
+		break
+	}
+
+	verificationURI := s.config.verificationURI(ctx)
+	return connect.NewResponse(&v1.StartDeviceAuthResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                formatUserCode(userCode),
+		VerificationUri:         verificationURI,
+		VerificationUriComplete: verificationURI + "?code=" + formatUserCode(userCode),
+		ExpiresIn:               int32(s.config.CodeTTL / time.Second),
+		Interval:                int32(s.config.PollInterval / time.Second),
+	}), nil
+}
+
+// Poll returns a token once the user approved the login. Each device code
+// yields one token; polling faster than the interval slows the client down.
+func (s *Server) Poll(ctx context.Context, req *connect.Request[v1.PollDeviceAuthRequest]) (*connect.Response[v1.PollDeviceAuthResponse], error) {
+	deviceCodeHash := hash(req.Msg.DeviceCode)
+	authorization, err := s.queries.GetDeviceAuthorization(ctx, deviceCodeHash)
+	if err == sql.ErrNoRows {
+		return nil, apierror.InvalidArgument(ReasonInvalidDeviceCode, ErrInvalidDeviceCode)
+	}
+	if err != nil {
+		return nil, apierror.Internal("failed to get device authorization", err)
+	}
+
+	now := time.Now().UTC()
+	if !now.Before(authorization.ExpiresAt) {
+		return nil, apierror.FailedPrecondition(ReasonExpiredToken, ErrExpiredToken)
+	}
+
+	interval := time.Duration(authorization.IntervalSeconds) * time.Second
+	// only one poll per interval wins; concurrent and early ones slow down
+	recorded, err := s.queries.RecordDeviceAuthorizationPoll(ctx, sqlc.RecordDeviceAuthorizationPollParams{
+		Now:            sql.NullTime{Time: now, Valid: true},
+		DeviceCodeHash: deviceCodeHash,
+		PolledBefore:   sql.NullTime{Time: now.Add(-interval), Valid: true},
+	})
+	if err != nil {
+		return nil, apierror.Internal("failed to record poll", err)
+	}
+	if recorded == 0 {
+		interval += slowDownIncrease
+		if err := s.queries.SlowDownDeviceAuthorization(ctx, sqlc.SlowDownDeviceAuthorizationParams{
+			IntervalSeconds: int64(interval / time.Second),
+			LastPolledAt:    sql.NullTime{Time: now, Valid: true},
+			DeviceCodeHash:  deviceCodeHash,
+		}); err != nil {
+			return nil, apierror.Internal("failed to slow down polling", err)
+		}
+		return nil, apierror.ResourceExhausted(ReasonSlowDown, ErrSlowDown).WithRetryDelay(interval)
+	}
+
+	switch authorization.Status {
+	case StatusPending:
+		return nil, apierror.FailedPrecondition(ReasonAuthorizationPending, ErrAuthorizationPending).WithRetryDelay(interval)
+	case StatusDenied:
+		if _, err := s.queries.ConsumeDeviceAuthorization(ctx, sqlc.ConsumeDeviceAuthorizationParams{DeviceCodeHash: deviceCodeHash, Status: StatusDenied}); err != nil {
+			s.log(ctx).WarnContext(ctx, "failed to delete denied device authorization", "error", err)
+		}
+		return nil, apierror.PermissionDenied(ReasonAccessDenied, ErrAccessDenied)
+	}
+
+	// deleting the approved authorization makes sure only one poll gets a token
+	consumed, err := s.queries.ConsumeDeviceAuthorization(ctx, sqlc.ConsumeDeviceAuthorizationParams{DeviceCodeHash: deviceCodeHash, Status: StatusApproved})
+	if err != nil {
+		return nil, apierror.Internal("failed to consume device authorization", err)
+	}
+	if consumed == 0 {
+		return nil, apierror.InvalidArgument(ReasonInvalidDeviceCode, ErrInvalidDeviceCode)
+	}
+
+	user, err := s.queries.GetUser(ctx, authorization.UserID.Int64)
+	if err == sql.ErrNoRows {
+		return nil, apierror.Unauthenticated(auth.ReasonUserNotFound, auth.ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, apierror.Internal("failed to get user", err)
+	}
+	jwt, err := s.authService.GenerateJWT(user.ID, user.Email)
+	if err != nil {
+		return nil, apierror.Internal("failed to issue token", err)
+	}
+
+	s.log(ctx).InfoContext(ctx, "device logged in", "user_id", user.ID, "client_name", authorization.ClientName)
+
+	// the token is returned even in cookie mode: devices have no cookie jar
+	return connect.NewResponse(&v1.PollDeviceAuthResponse{
+		Jwt: jwt,
+		User: &v1.User{
+			Id:       user.ID,
+			Email:    user.Email,
+			GoogleId: user.GoogleID.String,
+			Name:     user.Name,
+		},
+	}), nil
+}
+
+// Lookup returns the name of the client that started a pending login, so the
+// user can check it before approving
+func (s *Server) Lookup(ctx context.Context, req *connect.Request[v1.LookupDeviceAuthRequest]) (*connect.Response[v1.LookupDeviceAuthResponse], error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, apierror.Unauthenticated(auth.ReasonMissingToken, auth.ErrUnauthorized)
+	}
+	now := time.Now().UTC()
+	if err := s.checkAttempts(ctx, userID, now); err != nil {
+		return nil, err
+	}
+
+	clientName, err := s.queries.GetPendingDeviceAuthorization(ctx, sqlc.GetPendingDeviceAuthorizationParams{
+		UserCode: normalizeUserCode(req.Msg.UserCode),
+		Now:      now,
+	})
+	if err == sql.ErrNoRows {
+		return nil, s.invalidUserCode(ctx, userID, now)
+	}
+	if err != nil {
+		return nil, apierror.Internal("failed to get device authorization", err)
+	}
+
+	return connect.NewResponse(&v1.LookupDeviceAuthResponse{ClientName: clientName}), nil
+}
+
+// Approve lets the device that started the login act as the current user
+func (s *Server) Approve(ctx context.Context, req *connect.Request[v1.ApproveDeviceAuthRequest]) (*connect.Response[v1.ApproveDeviceAuthResponse], error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, apierror.Unauthenticated(auth.ReasonMissingToken, auth.ErrUnauthorized)
+	}
+	// The device receives a token with full access, which a scoped credential can't grant
+	if auth.GetScopesFromContext(ctx) != nil {
+		return nil, apierror.PermissionDenied(auth.ReasonInsufficientScopes, auth.ErrInsufficientScopes)
+	}
+	now := time.Now().UTC()
+	if err := s.checkAttempts(ctx, userID, now); err != nil {
+		return nil, err
+	}
+
+	clientName, err := s.queries.ApproveDeviceAuthorization(ctx, sqlc.ApproveDeviceAuthorizationParams{
+		UserID:   sql.NullInt64{Int64: userID, Valid: true},
+		UserCode: normalizeUserCode(req.Msg.UserCode),
+		Now:      now,
+	})
+	if err == sql.ErrNoRows {
+		return nil, s.invalidUserCode(ctx, userID, now)
+	}
+	if err != nil {
+		return nil, apierror.Internal("failed to approve device authorization", err)
+	}
+
+	s.log(ctx).InfoContext(ctx, "device login approved", "user_id", userID, "client_name", clientName)
+
+	return connect.NewResponse(&v1.ApproveDeviceAuthResponse{ClientName: clientName}), nil
+}
+
+// Deny rejects a device login; the device learns so on its next poll
+func (s *Server) Deny(ctx context.Context, req *connect.Request[v1.DenyDeviceAuthRequest]) (*connect.Response[v1.DenyDeviceAuthResponse], error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, apierror.Unauthenticated(auth.ReasonMissingToken, auth.ErrUnauthorized)
+	}
+	now := time.Now().UTC()
+	if err := s.checkAttempts(ctx, userID, now); err != nil {
+		return nil, err
+	}
+
+	denied, err := s.queries.DenyDeviceAuthorization(ctx, sqlc.DenyDeviceAuthorizationParams{
+		UserCode: normalizeUserCode(req.Msg.UserCode),
+		Now:      now,
+	})
+	if err != nil {
+		return nil, apierror.Internal("failed to deny device authorization", err)
+	}
+	if denied == 0 {
+		return nil, s.invalidUserCode(ctx, userID, now)
+	}
+	return connect.NewResponse(&v1.DenyDeviceAuthResponse{}), nil
+}
+
+// checkAttempts fails once the user entered MaxFailedAttempts unknown user
+// codes within CodeTTL (RFC 8628, section 5.1); the retry delay is the time
+// until the oldest of those attempts no longer counts
+func (s *Server) checkAttempts(ctx context.Context, userID int64, now time.Time) error {
+	failures, err := s.queries.ListDeviceCodeFailures(ctx, sqlc.ListDeviceCodeFailuresParams{
+		UserID: userID,
+		Since:  now.Add(-s.config.CodeTTL),
+	})
+	if err != nil {
+		return apierror.Internal("failed to count user code attempts", err)
+	}
+	if len(failures) < s.config.MaxFailedAttempts {
+		return nil
+	}
+	oldest := failures[len(failures)-s.config.MaxFailedAttempts]
+	return apierror.ResourceExhausted(ReasonTooManyAttempts, ErrTooManyAttempts).
+		WithRetryDelay(oldest.Add(s.config.CodeTTL).Sub(now))
+}
+
+// invalidUserCode records a failed attempt of the user and returns the error for an unknown code
+func (s *Server) invalidUserCode(ctx context.Context, userID int64, now time.Time) error {
+	if err := s.queries.RecordDeviceCodeFailure(ctx, sqlc.RecordDeviceCodeFailureParams{UserID: userID, FailedAt: now}); err != nil {
+		return apierror.Internal("failed to record user code attempt", err)
+	}
+	s.log(ctx).WarnContext(ctx, "unknown device user code", "user_id", userID)
+	return apierror.NotFound(ReasonInvalidUserCode, ErrInvalidUserCode)
+}
+
+// RunCleanup deletes expired device authorizations and failed attempts every interval until ctx is cancelled
+func (s *Server) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.queries.DeleteExpiredDeviceAuthorizations(ctx, time.Now().UTC())
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.ErrorContext(ctx, "failed to delete expired device authorizations", "error", err)
+				}
+				continue
+			}
+			if deleted > 0 {
+				s.logger.DebugContext(ctx, "deleted expired device authorizations", "count", deleted)
+			}
+
+			// failures older than a code's lifetime no longer count toward the limit
+			deleted, err = s.queries.DeleteDeviceCodeFailures(ctx, time.Now().UTC().Add(-s.config.CodeTTL))
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.ErrorContext(ctx, "failed to delete device code failures", "error", err)
+				}
+				continue
+			}
+			if deleted > 0 {
+				s.logger.DebugContext(ctx, "deleted device code failures", "count", deleted)
+			}
+		}
+	}
+}
+
+// log returns the request-scoped logger
+func (s *Server) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
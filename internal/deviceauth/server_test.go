@@ -0,0 +1,188 @@
+package deviceauth_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/api/gen/go/v1/v1connect"
+	"github.com/damejeras/goose/db"
+	"github.com/damejeras/goose/db/sqlc"
+	"github.com/damejeras/goose/internal/apierror"
+	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/deviceauth"
+)
+
+func newServer(t *testing.T) (*deviceauth.Server, *sqlc.Queries) {
+	t.Helper()
+
+	return newServerWithConfig(t, deviceauth.Config{
+		CodeTTL:           10 * time.Minute,
+		PollInterval:      5 * time.Second,
+		MaxFailedAttempts: 2,
+		MaxPending:        2,
+	})
+}
+
+func newServerWithConfig(t *testing.T, config deviceauth.Config) (*deviceauth.Server, *sqlc.Queries) {
+	t.Helper()
+
+	logger := slog.New(slog.DiscardHandler)
+	database, err := db.Open(t.Context(), logger, filepath.Join(t.TempDir(), "goose.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	queries := sqlc.New(database)
+
+	authService := auth.NewService(auth.Config{JWTSecret: []byte("0123456789abcdef0123456789abcdef")})
+	return deviceauth.NewServer(authService, queries, logger, config), queries
+}
+
+// asUser returns a context authenticated as a new user
+func asUser(t *testing.T, queries *sqlc.Queries, email string) context.Context {
+	t.Helper()
+
+	user, err := queries.CreateUser(t.Context(), sqlc.CreateUserParams{Email: email, Name: email})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return context.WithValue(t.Context(), auth.UserIDContextKey, user.ID)
+}
+
+func assertReason(t *testing.T, err error, code connect.Code, reason string) {
+	t.Helper()
+
+	apiErr, ok := apierror.From(err)
+	if !ok || apiErr.Code() != code || apiErr.Reason() != reason {
+		t.Fatalf("error = %v, want %s %s", err, code, reason)
+	}
+}
+
+func TestLookupAndApprove(t *testing.T) {
+	server, queries := newServer(t)
+	ctx := asUser(t, queries, "user@example.com")
+
+	start, err := server.Start(t.Context(), connect.NewRequest(&v1.StartDeviceAuthRequest{ClientName: "goosectl on build-01"}))
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	lookup, err := server.Lookup(ctx, connect.NewRequest(&v1.LookupDeviceAuthRequest{UserCode: start.Msg.UserCode}))
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if got := lookup.Msg.GetClientName(); got != "goosectl on build-01" {
+		t.Errorf("Lookup client name = %q, want goosectl on build-01", got)
+	}
+
+	if _, err := server.Approve(ctx, connect.NewRequest(&v1.ApproveDeviceAuthRequest{UserCode: start.Msg.UserCode})); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	// an approved login is no longer pending
+	_, err = server.Lookup(ctx, connect.NewRequest(&v1.LookupDeviceAuthRequest{UserCode: start.Msg.UserCode}))
+	assertReason(t, err, connect.CodeNotFound, deviceauth.ReasonInvalidUserCode)
+}
+
+func TestFailedAttemptsLimit(t *testing.T) {
+	server, queries := newServer(t)
+	guesser := asUser(t, queries, "guesser@example.com")
+	owner := asUser(t, queries, "owner@example.com")
+
+	start, err := server.Start(t.Context(), connect.NewRequest(&v1.StartDeviceAuthRequest{ClientName: "goosectl"}))
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	_, err = server.Lookup(guesser, connect.NewRequest(&v1.LookupDeviceAuthRequest{UserCode: "BCDF-GHJK"}))
+	assertReason(t, err, connect.CodeNotFound, deviceauth.ReasonInvalidUserCode)
+	_, err = server.Deny(guesser, connect.NewRequest(&v1.DenyDeviceAuthRequest{UserCode: "BCDF-GHJL"}))
+	assertReason(t, err, connect.CodeNotFound, deviceauth.ReasonInvalidUserCode)
+
+	// the limit applies even to the right code
+	_, err = server.Approve(guesser, connect.NewRequest(&v1.ApproveDeviceAuthRequest{UserCode: start.Msg.UserCode}))
+	assertReason(t, err, connect.CodeResourceExhausted, deviceauth.ReasonTooManyAttempts)
+
+	// other users are not limited by the guesser's failures
+	if _, err := server.Approve(owner, connect.NewRequest(&v1.ApproveDeviceAuthRequest{UserCode: start.Msg.UserCode})); err != nil {
+		t.Fatalf("Approve by another user: %v", err)
+	}
+}
+
+func TestPendingLimit(t *testing.T) {
+	server, queries := newServer(t)
+	ctx := asUser(t, queries, "user@example.com")
+
+	var first *v1.StartDeviceAuthResponse
+	for i := range 2 {
+		start, err := server.Start(t.Context(), connect.NewRequest(&v1.StartDeviceAuthRequest{ClientName: "goosectl"}))
+		if err != nil {
+			t.Fatalf("Start %d: %v", i+1, err)
+		}
+		if first == nil {
+			first = start.Msg
+		}
+	}
+	_, err := server.Start(t.Context(), connect.NewRequest(&v1.StartDeviceAuthRequest{ClientName: "goosectl"}))
+	assertReason(t, err, connect.CodeResourceExhausted, deviceauth.ReasonTooManyPending)
+
+	// an approved login no longer counts
+	if _, err := server.Approve(ctx, connect.NewRequest(&v1.ApproveDeviceAuthRequest{UserCode: first.UserCode})); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if _, err := server.Start(t.Context(), connect.NewRequest(&v1.StartDeviceAuthRequest{ClientName: "goosectl"})); err != nil {
+		t.Fatalf("Start after approval: %v", err)
+	}
+}
+
+func TestVerificationURI(t *testing.T) {
+	config := deviceauth.Config{CodeTTL: 10 * time.Minute, PollInterval: 5 * time.Second, MaxFailedAttempts: 2, MaxPending: 10}
+	serve := func(t *testing.T, config deviceauth.Config) *httptest.Server {
+		server, _ := newServerWithConfig(t, config)
+		path, handler := v1connect.NewDeviceAuthServiceHandler(server)
+		mux := http.NewServeMux()
+		mux.Handle(path, deviceauth.BaseURLMiddleware(handler))
+		ts := httptest.NewServer(mux)
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	start := func(t *testing.T, ts *httptest.Server, header http.Header) *v1.StartDeviceAuthResponse {
+		t.Helper()
+		req := connect.NewRequest(&v1.StartDeviceAuthRequest{ClientName: "goosectl"})
+		for name, values := range header {
+			req.Header()[name] = values
+		}
+		res, err := v1connect.NewDeviceAuthServiceClient(ts.Client(), ts.URL).Start(t.Context(), req)
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		return res.Msg
+	}
+
+	ts := serve(t, config)
+	res := start(t, ts, nil)
+	if want := ts.URL + "/device"; res.VerificationUri != want {
+		t.Errorf("verification_uri = %q, want %q", res.VerificationUri, want)
+	}
+	if want := ts.URL + "/device?code=" + res.UserCode; res.VerificationUriComplete != want {
+		t.Errorf("verification_uri_complete = %q, want %q", res.VerificationUriComplete, want)
+	}
+
+	// behind a TLS-terminating proxy
+	res = start(t, ts, http.Header{"X-Forwarded-Proto": {"https"}})
+	if want := "https://" + strings.TrimPrefix(ts.URL, "http://") + "/device"; res.VerificationUri != want {
+		t.Errorf("verification_uri behind a proxy = %q, want %q", res.VerificationUri, want)
+	}
+
+	config.VerificationURL = "https://goose.example.com/device"
+	if res := start(t, serve(t, config), nil); res.VerificationUri != config.VerificationURL {
+		t.Errorf("verification_uri = %q, want the configured %q", res.VerificationUri, config.VerificationURL)
+	}
+}
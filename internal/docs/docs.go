@@ -0,0 +1,27 @@
+// Package docs serves the OpenAPI document and a page that renders it
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// DocumentPath is where the OpenAPI document is served; the docs page fetches it from there
+const DocumentPath = "/openapi.json"
+
+//go:embed index.html
+var page []byte
+
+// Register serves the OpenAPI document at /openapi.json and the docs page at /docs
+func Register(mux *http.ServeMux, document []byte) {
+	mux.HandleFunc("GET "+DocumentPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write(document)
+	})
+	mux.HandleFunc("GET /docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write(page)
+	})
+}
@@ -0,0 +1,104 @@
+// Package headers sets the CORS and security headers of HTTP responses
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/idempotency"
+	"github.com/damejeras/goose/internal/logging"
+	"github.com/rs/cors"
+)
+
+type CORSConfig struct {
+	// AllowedOrigins lists the origins browsers may call the API from, e.g.
+	// "https://app.example.com". An origin may contain one "*" wildcard, as in
+	// "https://*.example.com"; "*" alone allows every origin. Empty disables CORS.
+	AllowedOrigins []string
+	// AllowCredentials lets cross-origin requests carry the session cookie
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// Validate checks the origins
+func (c CORSConfig) Validate() error {
+	var errs []error
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				errs = append(errs, errors.New("allow_credentials can't be combined with the \"*\" origin"))
+			}
+			continue
+		}
+		if strings.Count(origin, "*") > 1 {
+			errs = append(errs, fmt.Errorf("origin %q has more than one wildcard", origin))
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "*", "wildcard", 1))
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("origin %q must be scheme://host[:port]", origin))
+		}
+	}
+	if c.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("max_age must not be negative, got %s", c.MaxAge))
+	}
+	return errors.Join(errs...)
+}
+
+// Enabled reports whether any origin is allowed
+func (c CORSConfig) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+var (
+	// corsAllowedHeaders are the request headers of the Connect, gRPC-Web and
+	// gRPC protocols, and the ones the API reads itself
+	corsAllowedHeaders = []string{
+		"Content-Type",
+		"Connect-Protocol-Version",
+		"Connect-Timeout-Ms",
+		"Connect-Content-Encoding",
+		"Connect-Accept-Encoding",
+		"Grpc-Timeout",
+		"Grpc-Encoding",
+		"Grpc-Accept-Encoding",
+		"X-Grpc-Web",
+		"X-User-Agent",
+		"Authorization",
+		auth.CSRFHeader,
+		idempotency.Header,
+		logging.RequestIDHeader,
+	}
+	// corsExposedHeaders are the response headers clients need to read:
+	// gRPC-Web sends errors in headers when a call fails before any message
+	corsExposedHeaders = []string{
+		"Grpc-Status",
+		"Grpc-Message",
+		"Grpc-Status-Details-Bin",
+		"Connect-Content-Encoding",
+		"Retry-After",
+		idempotency.ReplayedHeader,
+		logging.RequestIDHeader,
+	}
+)
+
+// CORS answers preflight requests and adds CORS headers for the allowed origins
+func CORS(config CORSConfig) func(http.Handler) http.Handler {
+	c := cors.New(cors.Options{
+		AllowedOrigins: config.AllowedOrigins,
+		// Connect uses GET for side-effect-free procedures and POST for the
+		// rest; the REST routes use the other methods too
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowedHeaders:   corsAllowedHeaders,
+		ExposedHeaders:   corsExposedHeaders,
+		AllowCredentials: config.AllowCredentials,
+		MaxAge:           int(config.MaxAge.Seconds()),
+	})
+	return c.Handler
+}
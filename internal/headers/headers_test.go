@@ -0,0 +1,196 @@
+package headers_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/headers"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestCORSOrigins(t *testing.T) {
+	handler := headers.CORS(headers.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com", "https://*.preview.example.com"},
+	})(ok)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{origin: "https://app.example.com", allowed: true},
+		{origin: "https://pr-12.preview.example.com", allowed: true},
+		{origin: "http://app.example.com"},
+		{origin: "https://preview.example.com"},
+		{origin: "https://evil.com"},
+		{origin: "https://app.example.com.evil.com"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api.v1.AuthService/GetCurrentUser", nil)
+		req.Header.Set("Origin", tt.origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		got := rec.Header().Get("Access-Control-Allow-Origin")
+		if tt.allowed && got != tt.origin {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want the origin", tt.origin, got)
+		}
+		if !tt.allowed && got != "" {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want none", tt.origin, got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("%s: Access-Control-Allow-Credentials = %q without allow_credentials", tt.origin, got)
+		}
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	handler := headers.CORS(headers.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowCredentials: true,
+	})(ok)
+
+	req := httptest.NewRequest(http.MethodPost, "/api.v1.AuthService/GetCurrentUser", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "Retry-After") {
+		t.Errorf("Access-Control-Expose-Headers = %q, want Retry-After among them", got)
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := headers.CORS(headers.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		MaxAge:         2 * time.Hour,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("preflight request reached the handler")
+	}))
+
+	preflight := func(origin, method, headers string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/v1/api-keys/1", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", headers)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// browsers send the requested headers lowercase and sorted
+	requested := "authorization,content-type,idempotency-key," + strings.ToLower(auth.CSRFHeader)
+	rec := preflight("https://app.example.com", http.MethodPatch, requested)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("preflight status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != requested {
+		t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, requested)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != http.MethodPatch {
+		t.Errorf("Access-Control-Allow-Methods = %q, want PATCH", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "7200" {
+		t.Errorf("Access-Control-Max-Age = %q, want 7200", got)
+	}
+
+	if got := preflight("https://evil.com", http.MethodPatch, "content-type").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("preflight from another origin allowed %q", got)
+	}
+	if got := preflight("https://app.example.com", http.MethodPost, "x-unknown").Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("preflight with an unknown header allowed %q", got)
+	}
+}
+
+func TestCORSValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config headers.CORSConfig
+		valid  bool
+	}{
+		{name: "origins", config: headers.CORSConfig{AllowedOrigins: []string{"https://app.example.com", "http://localhost:5173", "https://*.example.com"}}, valid: true},
+		{name: "any origin", config: headers.CORSConfig{AllowedOrigins: []string{"*"}}, valid: true},
+		{name: "any origin with credentials", config: headers.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}},
+		{name: "two wildcards", config: headers.CORSConfig{AllowedOrigins: []string{"https://*.*.example.com"}}},
+		{name: "path", config: headers.CORSConfig{AllowedOrigins: []string{"https://app.example.com/app"}}},
+		{name: "no scheme", config: headers.CORSConfig{AllowedOrigins: []string{"app.example.com"}}},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate = %v, want valid %t", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestSecurity(t *testing.T) {
+	config := headers.SecurityConfig{
+		HSTSMaxAge:     365 * 24 * time.Hour,
+		FrameOptions:   headers.FrameOptionsDeny,
+		ReferrerPolicy: "strict-origin-when-cross-origin",
+	}
+
+	serve := func(config headers.SecurityConfig, https, forwarded bool) http.Header {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if https {
+			req.TLS = &tls.ConnectionState{}
+		}
+		if forwarded {
+			req.Header.Set("X-Forwarded-Proto", "https")
+		}
+		rec := httptest.NewRecorder()
+		headers.Security(config)(ok).ServeHTTP(rec, req)
+		return rec.Header()
+	}
+
+	h := serve(config, false, false)
+	for name, want := range map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Content-Security-Policy":   "frame-ancestors 'none'",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"Strict-Transport-Security": "",
+	} {
+		if got := h.Get(name); got != want {
+			t.Errorf("HTTP: %s = %q, want %q", name, got, want)
+		}
+	}
+
+	for name, h := range map[string]http.Header{
+		"HTTPS":                serve(config, true, false),
+		"HTTPS behind a proxy": serve(config, false, true),
+	} {
+		if got := h.Get("Strict-Transport-Security"); got != "max-age=31536000" {
+			t.Errorf("%s: Strict-Transport-Security = %q, want max-age=31536000", name, got)
+		}
+	}
+
+	config.HSTSIncludeSubdomains = true
+	if got := serve(config, true, false).Get("Strict-Transport-Security"); got != "max-age=31536000; includeSubDomains" {
+		t.Errorf("Strict-Transport-Security with subdomains = %q", got)
+	}
+
+	config = headers.SecurityConfig{FrameOptions: headers.FrameOptionsSameOrigin}
+	h = serve(config, true, false)
+	if got := h.Get("Content-Security-Policy"); got != "frame-ancestors 'self'" {
+		t.Errorf("SAMEORIGIN: Content-Security-Policy = %q", got)
+	}
+	if got := h.Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("Strict-Transport-Security = %q with hsts_max_age 0", got)
+	}
+	if got := h.Get("Referrer-Policy"); got != "" {
+		t.Errorf("Referrer-Policy = %q with an empty policy", got)
+	}
+}
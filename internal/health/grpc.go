@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"connectrpc.com/connect"
+	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ServiceName is the fully-qualified name of the gRPC health checking service
+const ServiceName = "grpc.health.v1.Health"
+
+const (
+	checkProcedure = "/" + ServiceName + "/Check"
+	watchProcedure = "/" + ServiceName + "/Watch"
+	listProcedure  = "/" + ServiceName + "/List"
+)
+
+var errUnknownService = errors.New("unknown service")
+
+// NewHandler serves the grpc.health.v1.Health service over the Connect, gRPC
+// and gRPC-Web protocols, returning the path to mount it on
+func NewHandler(checker *Checker, opts ...connect.HandlerOption) (string, http.Handler) {
+	methods := healthv1.File_grpc_health_v1_health_proto.Services().ByName("Health").Methods()
+
+	mux := http.NewServeMux()
+	mux.Handle(checkProcedure, connect.NewUnaryHandler(
+		checkProcedure,
+		checker.handleCheck,
+		connect.WithSchema(methods.ByName("Check")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	))
+	mux.Handle(watchProcedure, connect.NewServerStreamHandler(
+		watchProcedure,
+		checker.handleWatch,
+		connect.WithSchema(methods.ByName("Watch")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	))
+	mux.Handle(listProcedure, connect.NewUnaryHandler(
+		listProcedure,
+		checker.handleList,
+		connect.WithSchema(methods.ByName("List")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	))
+	return "/" + ServiceName + "/", mux
+}
+
+func (c *Checker) handleCheck(ctx context.Context, req *connect.Request[healthv1.HealthCheckRequest]) (*connect.Response[healthv1.HealthCheckResponse], error) {
+	status, ok := c.Check(ctx, req.Msg.GetService())
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("%w: %q", errUnknownService, req.Msg.GetService()))
+	}
+	return connect.NewResponse(&healthv1.HealthCheckResponse{Status: status}), nil
+}
+
+// handleWatch sends the current status, then every change until the client
+// goes away or the checker shuts down. Unknown services are reported as
+// SERVICE_UNKNOWN rather than failing, as the protocol requires.
+func (c *Checker) handleWatch(ctx context.Context, req *connect.Request[healthv1.HealthCheckRequest], stream *connect.ServerStream[healthv1.HealthCheckResponse]) error {
+	var last Status = -1
+	for {
+		changed := c.changes()
+
+		status, ok := c.Check(ctx, req.Msg.GetService())
+		if !ok {
+			status = healthv1.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if status != last {
+			if err := stream.Send(&healthv1.HealthCheckResponse{Status: status}); err != nil {
+				return err
+			}
+			last = status
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.done:
+			// Shutdown has already sent NOT_SERVING through changed, unless
+			// this stream was opened afterwards and got it on the first pass
+			if last != StatusNotServing {
+				return stream.Send(&healthv1.HealthCheckResponse{Status: StatusNotServing})
+			}
+			return nil
+		case <-changed:
+		}
+	}
+}
+
+func (c *Checker) handleList(ctx context.Context, req *connect.Request[healthv1.HealthListRequest]) (*connect.Response[healthv1.HealthListResponse], error) {
+	statuses := make(map[string]*healthv1.HealthCheckResponse)
+	for _, service := range c.Services() {
+		status, _ := c.Check(ctx, service)
+		statuses[service] = &healthv1.HealthCheckResponse{Status: status}
+	}
+	return connect.NewResponse(&healthv1.HealthListResponse{Statuses: statuses}), nil
+}
@@ -0,0 +1,160 @@
+// Package health serves liveness and readiness probes and the standard
+// grpc.health.v1.Health service.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Status is a grpc.health.v1 serving status
+type Status = healthv1.HealthCheckResponse_ServingStatus
+
+const (
+	StatusUnknown    = healthv1.HealthCheckResponse_UNKNOWN
+	StatusServing    = healthv1.HealthCheckResponse_SERVING
+	StatusNotServing = healthv1.HealthCheckResponse_NOT_SERVING
+)
+
+// probeTimeout bounds how long a single dependency probe may take
+const probeTimeout = 2 * time.Second
+
+// Probe checks that a dependency the server needs to handle requests is usable
+type Probe struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Checker tracks the serving status of the server as a whole (the empty service
+// name) and of each registered service. Every status starts out NOT_SERVING;
+// the server flips them once it is listening and back as soon as shutdown
+// begins, so load balancers stop routing to it. A SERVING status is only
+// reported while every probe passes.
+type Checker struct {
+	probes []Probe
+
+	mu       sync.RWMutex
+	statuses map[string]Status
+	// changed is closed and replaced whenever a status changes
+	changed chan struct{}
+	// done is closed by Shutdown to end Watch streams
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// NewChecker creates a checker for the named services, e.g. "api.v1.AuthService"
+func NewChecker(probes []Probe, services ...string) *Checker {
+	statuses := map[string]Status{"": StatusNotServing}
+	for _, service := range services {
+		statuses[service] = StatusNotServing
+	}
+	return &Checker{
+		probes:   probes,
+		statuses: statuses,
+		changed:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Shutdown marks everything not serving and ends open Watch streams, which
+// would otherwise hold their connections open while the server drains
+func (c *Checker) Shutdown() {
+	c.SetServing(false)
+	c.doneOnce.Do(func() { close(c.done) })
+}
+
+// SetServing marks the server and every registered service as serving or not serving
+func (c *Checker) SetServing(serving bool) {
+	status := StatusNotServing
+	if serving {
+		status = StatusServing
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for service := range c.statuses {
+		c.statuses[service] = status
+	}
+	c.notifyLocked()
+}
+
+// SetStatus sets the status of a single registered service
+func (c *Checker) SetStatus(service string, status Status) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.statuses[service]; !ok {
+		return
+	}
+	c.statuses[service] = status
+	c.notifyLocked()
+}
+
+func (c *Checker) notifyLocked() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Ready reports whether the server as a whole is serving, without running probes
+func (c *Checker) Ready() bool {
+	status, _ := c.status("")
+	return status == StatusServing
+}
+
+// Check returns the status of service, running the probes when it is marked
+// serving. ok is false for services that were never registered.
+func (c *Checker) Check(ctx context.Context, service string) (status Status, ok bool) {
+	status, ok = c.status(service)
+	if !ok || status != StatusServing {
+		return status, ok
+	}
+	for _, result := range c.runProbes(ctx) {
+		if result.err != nil {
+			return StatusNotServing, true
+		}
+	}
+	return StatusServing, true
+}
+
+// Services returns the names of every registered service, including "" for the server
+func (c *Checker) Services() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	services := make([]string, 0, len(c.statuses))
+	for service := range c.statuses {
+		services = append(services, service)
+	}
+	return services
+}
+
+func (c *Checker) status(service string) (Status, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	status, ok := c.statuses[service]
+	return status, ok
+}
+
+// changes returns a channel that is closed on the next status change
+func (c *Checker) changes() <-chan struct{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.changed
+}
+
+type probeResult struct {
+	name string
+	err  error
+}
+
+func (c *Checker) runProbes(ctx context.Context) []probeResult {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	results := make([]probeResult, len(c.probes))
+	for i, probe := range c.probes {
+		results[i] = probeResult{name: probe.Name, err: probe.Check(ctx)}
+	}
+	return results
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/damejeras/goose/internal/logging"
+)
+
+// readinessResponse is the body of /readyz
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// LivenessHandler serves /healthz. It succeeds as long as the process can handle
+// HTTP requests and deliberately ignores dependencies, so a database outage does
+// not get the server restarted.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, readinessResponse{Status: "ok"})
+	})
+}
+
+// ReadinessHandler serves /readyz. It fails while the server is starting or
+// shutting down and whenever a probe fails, reporting whether each probe
+// passed. /readyz may be served on the public port, so why a probe failed is
+// only logged.
+func (c *Checker) ReadinessHandler(logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := readinessResponse{Status: "ok", Checks: make(map[string]string)}
+		code := http.StatusOK
+
+		if !c.Ready() {
+			res.Status = "not ready"
+			code = http.StatusServiceUnavailable
+		}
+		for _, result := range c.runProbes(r.Context()) {
+			if result.err != nil {
+				logging.FromContext(r.Context(), logger).WarnContext(r.Context(), "readiness probe failed", "probe", result.name, "error", result.err)
+				res.Checks[result.name] = "failed"
+				res.Status = "not ready"
+				code = http.StatusServiceUnavailable
+				continue
+			}
+			res.Checks[result.name] = "ok"
+		}
+
+		writeJSON(w, code, res)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,74 @@
+package health_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/damejeras/goose/internal/health"
+)
+
+func TestReadinessHidesProbeErrors(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	checker := health.NewChecker([]health.Probe{
+		{Name: "database", Check: func(ctx context.Context) error {
+			return errors.New("unable to open database file /var/lib/goose/goose.db")
+		}},
+		{Name: "migrations", Check: func(ctx context.Context) error { return nil }},
+	})
+	checker.SetServing(true)
+
+	rec := httptest.NewRecorder()
+	checker.ReadinessHandler(logger).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	var body struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	if body.Status != "not ready" || body.Checks["database"] != "failed" || body.Checks["migrations"] != "ok" {
+		t.Errorf("body = %+v, want the database probe failed and migrations ok", body)
+	}
+	if strings.Contains(rec.Body.String(), "/var/lib") {
+		t.Errorf("response exposes the probe error: %s", rec.Body)
+	}
+	if !strings.Contains(logs.String(), "/var/lib/goose/goose.db") {
+		t.Errorf("probe error was not logged: %s", logs.String())
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	checker := health.NewChecker([]health.Probe{
+		{Name: "database", Check: func(ctx context.Context) error { return nil }},
+	})
+	handler := checker.ReadinessHandler(slog.New(slog.DiscardHandler))
+
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("before serving: status %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	checker.SetServing(true)
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("serving: status %d, want %d", code, http.StatusOK)
+	}
+	checker.Shutdown()
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("after shutdown: status %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,327 @@
+// Package idempotency lets clients retry mutating RPCs safely. A request that
+// carries an Idempotency-Key header is executed once per key; retries with the
+// same key and payload replay the stored response.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/damejeras/goose/db/sqlc"
+	"github.com/damejeras/goose/internal/apierror"
+	"github.com/damejeras/goose/internal/logging"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	// Header carries the client-chosen key, e.g. a UUID generated per logical operation
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from an earlier request
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+var (
+	ErrInvalidKey = errors.New("idempotency key must be 1 to 255 printable ASCII characters")
+	ErrKeyReused  = errors.New("idempotency key was already used with a different request")
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// Reasons reported in the google.rpc.ErrorInfo of idempotency errors
+const (
+	ReasonInvalidKey = "INVALID_IDEMPOTENCY_KEY"
+	ReasonKeyReused  = "IDEMPOTENCY_KEY_REUSED"
+	ReasonInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+)
+
+type Config struct {
+	// TTL is how long a completed response can be replayed
+	TTL time.Duration
+	// LockTimeout is how long a key stays reserved by a request that never
+	// completed, e.g. because the server crashed
+	LockTimeout time.Duration
+	// Scope returns the namespace keys are unique within, usually the caller.
+	// Requests without a scope are not deduplicated.
+	Scope func(ctx context.Context) (string, bool)
+	// Secret keys the hashes of stored idempotency keys and the encryption of
+	// stored responses, usually the JWT secret. Responses stored under another
+	// secret can't be replayed.
+	Secret []byte
+}
+
+// Interceptor deduplicates unary calls to procedures that aren't declared
+// NO_SIDE_EFFECTS or IDEMPOTENT. Failed calls release their key so they can
+// be retried; only successful responses are stored, and response headers are
+// not replayed.
+//
+// Stored responses may contain secrets, such as a newly created API key, so
+// they are encrypted with a key derived from the idempotency key and the
+// server secret; the idempotency key itself is only stored as an HMAC. Reading
+// the database alone doesn't reveal responses, however guessable the key.
+type Interceptor struct {
+	queries *sqlc.Queries
+	logger  *slog.Logger
+	config  Config
+}
+
+// NewInterceptor creates an idempotency interceptor
+func NewInterceptor(queries *sqlc.Queries, logger *slog.Logger, config Config) *Interceptor {
+	return &Interceptor{
+		queries: queries,
+		logger:  logger,
+		config:  config,
+	}
+}
+
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		spec := req.Spec()
+		key := req.Header().Get(Header)
+		if spec.IsClient || spec.IdempotencyLevel != connect.IdempotencyUnknown || key == "" {
+			return next(ctx, req)
+		}
+		scope, ok := i.config.Scope(ctx)
+		if !ok {
+			return next(ctx, req)
+		}
+		if !validKey(key) {
+			return nil, apierror.InvalidArgument(ReasonInvalidKey, ErrInvalidKey).WithFieldViolation(Header, ErrInvalidKey.Error())
+		}
+		msg, ok := req.Any().(proto.Message)
+		if !ok {
+			return next(ctx, req)
+		}
+
+		fingerprint, err := fingerprint(spec.Procedure, msg)
+		if err != nil {
+			return nil, apierror.Internal("failed to fingerprint request", err)
+		}
+		keyHash := hashKey(i.config.Secret, key)
+
+		now := time.Now().UTC()
+		reserved, err := i.queries.ReserveIdempotencyKey(ctx, sqlc.ReserveIdempotencyKeyParams{
+			Scope:       scope,
+			KeyHash:     keyHash,
+			Fingerprint: fingerprint,
+			Now:         now,
+			LockedUntil: now.Add(i.config.LockTimeout),
+			ExpiresAt:   now.Add(i.config.TTL),
+		})
+		if err != nil {
+			return nil, apierror.Internal("failed to reserve idempotency key", err)
+		}
+		if reserved == 0 {
+			return i.replay(ctx, spec, scope, key, fingerprint)
+		}
+
+		// the outcome must be recorded even if the caller has gone away
+		storeCtx := context.WithoutCancel(ctx)
+		res, err := next(ctx, req)
+		if err != nil {
+			if releaseErr := i.queries.ReleaseIdempotencyKey(storeCtx, sqlc.ReleaseIdempotencyKeyParams{Scope: scope, KeyHash: keyHash}); releaseErr != nil {
+				i.log(ctx).WarnContext(ctx, "failed to release idempotency key", "error", releaseErr)
+			}
+			return nil, err
+		}
+
+		if err := i.complete(storeCtx, scope, key, res); err != nil {
+			// the call succeeded; retries get ErrInProgress until the lock times out
+			i.log(ctx).ErrorContext(ctx, "failed to store idempotent response", "error", err)
+		}
+		return res, nil
+	}
+}
+
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler leaves streams alone: their responses can't be replayed
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// complete encrypts and stores the response of a successful call
+func (i *Interceptor) complete(ctx context.Context, scope, key string, res connect.AnyResponse) error {
+	msg, ok := res.Any().(proto.Message)
+	if !ok {
+		return fmt.Errorf("response is %T, not a proto message", res.Any())
+	}
+	plaintext, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal response: %w", err)
+	}
+	ciphertext, err := seal(i.config.Secret, scope, key, plaintext)
+	if err != nil {
+		return err
+	}
+	return i.queries.CompleteIdempotencyKey(ctx, sqlc.CompleteIdempotencyKeyParams{
+		Response: ciphertext,
+		Scope:    scope,
+		KeyHash:  hashKey(i.config.Secret, key),
+	})
+}
+
+// replay returns the stored response for a key reserved by an earlier request
+func (i *Interceptor) replay(ctx context.Context, spec connect.Spec, scope, key string, fingerprint []byte) (connect.AnyResponse, error) {
+	record, err := i.queries.GetIdempotencyKey(ctx, sqlc.GetIdempotencyKeyParams{Scope: scope, KeyHash: hashKey(i.config.Secret, key)})
+	if errors.Is(err, sql.ErrNoRows) {
+		// the earlier request failed and released the key after we tried to reserve it
+		return nil, inProgress()
+	}
+	if err != nil {
+		return nil, apierror.Internal("failed to look up idempotency key", err)
+	}
+	if !bytes.Equal(record.Fingerprint, fingerprint) {
+		return nil, apierror.InvalidArgument(ReasonKeyReused, ErrKeyReused)
+	}
+	if record.Response == nil {
+		return nil, inProgress()
+	}
+
+	method, ok := spec.Schema.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, apierror.Internal("failed to replay response", fmt.Errorf("%s has no method descriptor", spec.Procedure))
+	}
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(method.Output().FullName())
+	if err != nil {
+		return nil, apierror.Internal("failed to replay response", err)
+	}
+	plaintext, err := open(i.config.Secret, scope, key, record.Response)
+	if err != nil {
+		return nil, apierror.Internal("failed to replay response", err)
+	}
+	msg := messageType.New().Interface()
+	if err := proto.Unmarshal(plaintext, msg); err != nil {
+		return nil, apierror.Internal("failed to replay response", err)
+	}
+
+	i.log(ctx).DebugContext(ctx, "replaying idempotent response", "stored_at", record.CreatedAt)
+	res := connect.NewResponse(&replayed{Message: msg})
+	res.Header().Set(ReplayedHeader, "true")
+	return res, nil
+}
+
+// replayed wraps a response message whose Go type is only known at runtime.
+// Connect sends res.Any() with the codec, which only needs a proto.Message.
+type replayed struct {
+	proto.Message
+}
+
+// RunCleanup deletes expired keys every interval until ctx is cancelled
+func (i *Interceptor) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := i.queries.DeleteExpiredIdempotencyKeys(ctx, time.Now().UTC())
+			if err != nil {
+				if ctx.Err() == nil {
+					i.logger.ErrorContext(ctx, "failed to delete expired idempotency keys", "error", err)
+				}
+				continue
+			}
+			if deleted > 0 {
+				i.logger.DebugContext(ctx, "deleted expired idempotency keys", "count", deleted)
+			}
+		}
+	}
+}
+
+// log returns the request-scoped logger
+func (i *Interceptor) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, i.logger)
+}
+
+func inProgress() error {
+	return apierror.New(connect.CodeAborted, ReasonInProgress, ErrInProgress).WithRetryDelay(time.Second)
+}
+
+func validKey(key string) bool {
+	if len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// fingerprint identifies the procedure and payload of a request
+func fingerprint(procedure string, msg proto.Message) ([]byte, error) {
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte(procedure))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return h.Sum(nil), nil
+}
+
+// hashKey identifies a stored idempotency key
+func hashKey(secret []byte, key string) []byte {
+	return derive(secret, "lookup\x00"+key)
+}
+
+// derive is an HMAC-SHA256 of data under the server secret, so values
+// derived from client-chosen keys can't be recomputed from the database alone
+func derive(secret []byte, data string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// seal encrypts a stored response with AES-256-GCM under a key derived from
+// the server secret, the scope and the idempotency key
+func seal(secret []byte, scope, key string, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(secret, scope, key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(secret []byte, scope, key string, ciphertext []byte) ([]byte, error) {
+	aead, err := newAEAD(secret, scope, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("stored response is truncated")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, nil)
+}
+
+func newAEAD(secret []byte, scope, key string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(derive(secret, "response\x00"+scope+"\x00"+key))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,283 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/api/gen/go/v1/v1connect"
+	"github.com/damejeras/goose/db"
+	"github.com/damejeras/goose/db/sqlc"
+	"github.com/damejeras/goose/internal/apierror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+)
+
+const testScope = "1"
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// idempotencyTest serves CreateAPIKey behind the idempotency interceptor. The
+// handler numbers its calls in the key ID and waits for block, when set.
+type idempotencyTest struct {
+	server  *httptest.Server
+	queries *sqlc.Queries
+	client  *connect.Client[v1.CreateAPIKeyRequest, v1.CreateAPIKeyResponse]
+	calls   atomic.Int64
+	entered chan struct{}
+	block   chan struct{}
+}
+
+func newIdempotencyTest(t *testing.T, config Config) *idempotencyTest {
+	t.Helper()
+
+	logger := slog.New(slog.DiscardHandler)
+	database, err := db.Open(t.Context(), logger, filepath.Join(t.TempDir(), "goose.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	it := &idempotencyTest{queries: sqlc.New(database)}
+	config.Secret = testSecret
+	config.Scope = func(ctx context.Context) (string, bool) { return testScope, true }
+	if config.TTL == 0 {
+		config.TTL = time.Hour
+	}
+	if config.LockTimeout == 0 {
+		config.LockTimeout = time.Minute
+	}
+
+	create := func(ctx context.Context, req *connect.Request[v1.CreateAPIKeyRequest]) (*connect.Response[v1.CreateAPIKeyResponse], error) {
+		call := it.calls.Add(1)
+		if it.block != nil {
+			it.entered <- struct{}{}
+			<-it.block
+		}
+		return connect.NewResponse(&v1.CreateAPIKeyResponse{
+			Id:     strconv.FormatInt(call, 10),
+			Name:   req.Msg.GetName(),
+			Key:    "gsk_secret",
+			Scopes: req.Msg.GetScopes(),
+		}), nil
+	}
+	method := v1.File_v1_apikey_proto.Services().ByName("APIKeyService").Methods().ByName("CreateAPIKey")
+	mux := http.NewServeMux()
+	mux.Handle(v1connect.APIKeyServiceCreateAPIKeyProcedure, connect.NewUnaryHandler(
+		v1connect.APIKeyServiceCreateAPIKeyProcedure,
+		create,
+		connect.WithSchema(method),
+		connect.WithInterceptors(apierror.NewInterceptor(logger), NewInterceptor(it.queries, logger, config)),
+	))
+	it.server = httptest.NewServer(mux)
+	t.Cleanup(it.server.Close)
+	it.client = connect.NewClient[v1.CreateAPIKeyRequest, v1.CreateAPIKeyResponse](it.server.Client(), it.server.URL+v1connect.APIKeyServiceCreateAPIKeyProcedure)
+	return it
+}
+
+func (it *idempotencyTest) create(ctx context.Context, key, name string) (*connect.Response[v1.CreateAPIKeyResponse], error) {
+	req := connect.NewRequest(&v1.CreateAPIKeyRequest{Name: name})
+	req.Header().Set(Header, key)
+	return it.client.CallUnary(ctx, req)
+}
+
+// assertReason fails unless err is a Connect error with the code and ErrorInfo reason
+func assertReason(t *testing.T, err error, code connect.Code, reason string) {
+	t.Helper()
+
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) || connectErr.Code() != code {
+		t.Fatalf("error = %v, want code %s", err, code)
+	}
+	for _, detail := range connectErr.Details() {
+		if info, err := detail.Value(); err == nil {
+			if info, ok := info.(*errdetails.ErrorInfo); ok && info.GetReason() == reason {
+				return
+			}
+		}
+	}
+	t.Fatalf("error = %v, want reason %s", err, reason)
+}
+
+func TestReplay(t *testing.T) {
+	it := newIdempotencyTest(t, Config{})
+
+	first, err := it.create(t.Context(), "key-1", "deploy")
+	if err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	second, err := it.create(t.Context(), "key-1", "deploy")
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+
+	if got := it.calls.Load(); got != 1 {
+		t.Errorf("handler ran %d times, want 1", got)
+	}
+	if !proto.Equal(first.Msg, second.Msg) {
+		t.Errorf("replayed %v, want %v", second.Msg, first.Msg)
+	}
+	if first.Header().Get(ReplayedHeader) != "" || second.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("%s = %q then %q, want it only on the replay", ReplayedHeader, first.Header().Get(ReplayedHeader), second.Header().Get(ReplayedHeader))
+	}
+
+	// another key runs the handler again
+	if _, err := it.create(t.Context(), "key-2", "deploy"); err != nil {
+		t.Fatalf("call with another key: %v", err)
+	}
+	if got := it.calls.Load(); got != 2 {
+		t.Errorf("handler ran %d times, want 2", got)
+	}
+}
+
+func TestStoredResponseNeedsSecret(t *testing.T) {
+	it := newIdempotencyTest(t, Config{})
+	if _, err := it.create(t.Context(), "key-1", "deploy"); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+
+	record, err := it.queries.GetIdempotencyKey(t.Context(), sqlc.GetIdempotencyKeyParams{Scope: testScope, KeyHash: hashKey(testSecret, "key-1")})
+	if err != nil {
+		t.Fatalf("GetIdempotencyKey: %v", err)
+	}
+	if bytes.Contains(record.Response, []byte("gsk_secret")) {
+		t.Error("stored response contains the API key in plaintext")
+	}
+	if _, err := open(testSecret, testScope, "key-1", record.Response); err != nil {
+		t.Errorf("open with the server secret: %v", err)
+	}
+	if _, err := open([]byte("another secret"), testScope, "key-1", record.Response); err == nil {
+		t.Error("stored response opens without the server secret")
+	}
+}
+
+func TestKeyReusedWithAnotherRequest(t *testing.T) {
+	it := newIdempotencyTest(t, Config{})
+
+	if _, err := it.create(t.Context(), "key-1", "deploy"); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	_, err := it.create(t.Context(), "key-1", "other")
+	assertReason(t, err, connect.CodeInvalidArgument, ReasonKeyReused)
+	if got := it.calls.Load(); got != 1 {
+		t.Errorf("handler ran %d times, want 1", got)
+	}
+}
+
+func TestInProgress(t *testing.T) {
+	it := newIdempotencyTest(t, Config{})
+	it.entered, it.block = make(chan struct{}), make(chan struct{})
+
+	done := make(chan error)
+	go func() {
+		_, err := it.create(context.Background(), "key-1", "deploy")
+		done <- err
+	}()
+	<-it.entered
+
+	_, err := it.create(t.Context(), "key-1", "deploy")
+	assertReason(t, err, connect.CodeAborted, ReasonInProgress)
+
+	close(it.block)
+	if err := <-done; err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+}
+
+func TestLockTimeoutTakeover(t *testing.T) {
+	it := newIdempotencyTest(t, Config{})
+	req := &v1.CreateAPIKeyRequest{Name: "deploy"}
+	fp, err := fingerprint(v1connect.APIKeyServiceCreateAPIKeyProcedure, req)
+	if err != nil {
+		t.Fatalf("fingerprint: %v", err)
+	}
+
+	// a request that reserved the key and never completed, e.g. because the server crashed
+	past := time.Now().UTC().Add(-time.Minute)
+	if _, err := it.queries.ReserveIdempotencyKey(t.Context(), sqlc.ReserveIdempotencyKeyParams{
+		Scope:       testScope,
+		KeyHash:     hashKey(testSecret, "key-1"),
+		Fingerprint: fp,
+		Now:         past,
+		LockedUntil: past.Add(time.Second),
+		ExpiresAt:   past.Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("ReserveIdempotencyKey: %v", err)
+	}
+
+	if _, err := it.create(t.Context(), "key-1", "deploy"); err != nil {
+		t.Fatalf("call after the lock timed out: %v", err)
+	}
+	if got := it.calls.Load(); got != 1 {
+		t.Errorf("handler ran %d times, want 1", got)
+	}
+}
+
+func TestExpiredKey(t *testing.T) {
+	it := newIdempotencyTest(t, Config{TTL: time.Millisecond})
+
+	if _, err := it.create(t.Context(), "key-1", "deploy"); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// an expired key is free again, even for another request
+	res, err := it.create(t.Context(), "key-1", "other")
+	if err != nil {
+		t.Fatalf("call after expiry: %v", err)
+	}
+	if res.Header().Get(ReplayedHeader) != "" || res.Msg.GetName() != "other" {
+		t.Errorf("call after expiry replayed %v", res.Msg)
+	}
+	if got := it.calls.Load(); got != 2 {
+		t.Errorf("handler ran %d times, want 2", got)
+	}
+}
+
+// TestReplayedBytes checks that the replayed wrapper serializes exactly like the original response
+func TestReplayedBytes(t *testing.T) {
+	it := newIdempotencyTest(t, Config{})
+	payload, err := proto.Marshal(&v1.CreateAPIKeyRequest{Name: "deploy", Scopes: []string{"api_keys:read"}})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+
+	call := func() []byte {
+		t.Helper()
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, it.server.URL+v1connect.APIKeyServiceCreateAPIKeyProcedure, bytes.NewReader(payload))
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/proto")
+		req.Header.Set(Header, "key-1")
+		res, err := it.server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, body %q", res.StatusCode, body)
+		}
+		return body
+	}
+
+	original, replay := call(), call()
+	if !bytes.Equal(original, replay) {
+		t.Errorf("replayed body %x, want %x", replay, original)
+	}
+}
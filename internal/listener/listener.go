@@ -0,0 +1,176 @@
+// Package listener opens the sockets the servers accept connections on. An
+// address is one of
+//
+//   - a TCP host:port, e.g. ":8080" or "127.0.0.1:9090"
+//   - a Unix socket path, "unix:/run/goose.sock" or "unix:///run/goose.sock"
+//   - "systemd:NAME", the sockets systemd passed with FileDescriptorName=NAME
+//   - "systemd", every socket passed by systemd that no "systemd:NAME" address claims
+//
+// With systemd socket activation the sockets outlive the process, so
+// connections queue up instead of being refused while goose restarts.
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/coreos/go-systemd/v22/activation"
+)
+
+const (
+	unixPrefix    = "unix:"
+	systemdPrefix = "systemd:"
+	systemd       = "systemd"
+)
+
+// Validate checks the syntax of an address without opening it
+func Validate(address string) error {
+	if address == systemd {
+		return nil
+	}
+	if name, ok := strings.CutPrefix(address, systemdPrefix); ok {
+		if name == "" {
+			return errors.New("systemd socket name is empty")
+		}
+		return nil
+	}
+	if path, ok := unixPath(address); ok {
+		if path == "" {
+			return errors.New("unix socket path is empty")
+		}
+		return nil
+	}
+	_, _, err := net.SplitHostPort(address)
+	return err
+}
+
+// Open opens the addresses of several servers at once and returns their
+// listeners in the same groups, so that named systemd sockets are claimed
+// before a bare "systemd" address takes the rest. Nothing is left open on error.
+func Open(groups ...[]string) ([][]net.Listener, error) {
+	var inherited map[string][]net.Listener
+	if slices.ContainsFunc(slices.Concat(groups...), isSystemd) {
+		var err error
+		if inherited, err = activation.ListenersWithNames(); err != nil {
+			return nil, fmt.Errorf("inherit systemd sockets: %w", err)
+		}
+	}
+
+	var opened []net.Listener
+	fail := func(err error) ([][]net.Listener, error) {
+		for _, ln := range opened {
+			ln.Close()
+		}
+		for _, lns := range inherited {
+			for _, ln := range lns {
+				ln.Close()
+			}
+		}
+		return nil, err
+	}
+
+	// One slot per address keeps the listeners in the order they were configured
+	slots := make([][][]net.Listener, len(groups))
+	for g, addresses := range groups {
+		slots[g] = make([][]net.Listener, len(addresses))
+		for a, address := range addresses {
+			switch {
+			case address == systemd:
+				continue
+			case strings.HasPrefix(address, systemdPrefix):
+				name := strings.TrimPrefix(address, systemdPrefix)
+				lns, ok := inherited[name]
+				if !ok {
+					return fail(fmt.Errorf("%s: systemd passed no socket named %q", address, name))
+				}
+				delete(inherited, name)
+				slots[g][a] = lns
+			default:
+				ln, err := listen(address)
+				if err != nil {
+					return fail(fmt.Errorf("listen on %s: %w", address, err))
+				}
+				slots[g][a] = []net.Listener{ln}
+			}
+			opened = append(opened, slots[g][a]...)
+		}
+	}
+
+	for g, addresses := range groups {
+		for a, address := range addresses {
+			if address != systemd {
+				continue
+			}
+			if len(inherited) == 0 {
+				return fail(errors.New("systemd: no unclaimed sockets were passed by systemd"))
+			}
+			names := make([]string, 0, len(inherited))
+			for name := range inherited {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			for _, name := range names {
+				slots[g][a] = append(slots[g][a], inherited[name]...)
+				delete(inherited, name)
+			}
+			opened = append(opened, slots[g][a]...)
+		}
+	}
+
+	// systemd may pass sockets meant for a future version of the config
+	for _, lns := range inherited {
+		for _, ln := range lns {
+			ln.Close()
+		}
+	}
+
+	listeners := make([][]net.Listener, len(groups))
+	for g := range slots {
+		listeners[g] = slices.Concat(slots[g]...)
+	}
+	return listeners, nil
+}
+
+// listen opens a TCP or Unix socket. A stale Unix socket left behind by a
+// previous process, one nothing accepts connections on, is removed first; a
+// socket still in use and any other file at the path are left alone.
+func listen(address string) (net.Listener, error) {
+	path, ok := unixPath(address)
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+
+	if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSocket != 0 {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if !errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, fmt.Errorf("check for a stale socket: %w", err)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// unixPath returns the socket path of a unix: address in either the
+// "unix:/path" or the URL "unix:///path" form
+func unixPath(address string) (string, bool) {
+	if path, ok := strings.CutPrefix(address, unixPrefix+"//"); ok {
+		return path, true
+	}
+	return strings.CutPrefix(address, unixPrefix)
+}
+
+func isSystemd(address string) bool {
+	return address == systemd || strings.HasPrefix(address, systemdPrefix)
+}
@@ -0,0 +1,219 @@
+package listener_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/damejeras/goose/internal/listener"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		address string
+		valid   bool
+	}{
+		{address: ":8080", valid: true},
+		{address: "127.0.0.1:9090", valid: true},
+		{address: "unix:/run/goose.sock", valid: true},
+		{address: "unix:///run/goose.sock", valid: true},
+		{address: "systemd", valid: true},
+		{address: "systemd:web", valid: true},
+		{address: "8080"},
+		{address: "unix:"},
+		{address: "unix://"},
+		{address: "systemd:"},
+	}
+	for _, tt := range tests {
+		if err := listener.Validate(tt.address); (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) = %v, want valid %t", tt.address, err, tt.valid)
+		}
+	}
+}
+
+// openOne opens a single address and returns its listener
+func openOne(t *testing.T, address string) (net.Listener, error) {
+	t.Helper()
+
+	listeners, err := listener.Open([]string{address})
+	if err != nil {
+		return nil, err
+	}
+	if len(listeners) != 1 || len(listeners[0]) != 1 {
+		t.Fatalf("Open(%q) returned %v, want one listener", address, listeners)
+	}
+	t.Cleanup(func() { listeners[0][0].Close() })
+	return listeners[0][0], nil
+}
+
+func TestOpenUnix(t *testing.T) {
+	for _, prefix := range []string{"unix:", "unix://"} {
+		t.Run(prefix, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "goose.sock")
+			ln, err := openOne(t, prefix+path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if got := ln.Addr().String(); got != path {
+				t.Errorf("listening on %q, want %q", got, path)
+			}
+		})
+	}
+}
+
+func TestOpenUnixStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goose.sock")
+
+	// a socket left behind by a process that crashed
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	if _, err := openOne(t, "unix:"+path); err != nil {
+		t.Fatalf("Open over a stale socket: %v", err)
+	}
+}
+
+func TestOpenUnixSocketInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goose.sock")
+	if _, err := openOne(t, "unix:"+path); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	_, err := openOne(t, "unix:"+path)
+	if err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Fatalf("Open of a socket in use = %v, want an in use error", err)
+	}
+	// the first listener still owns the path
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dial the first listener: %v", err)
+	}
+	conn.Close()
+}
+
+func TestOpenUnixKeepsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goose.sock")
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err := openOne(t, "unix:"+path); err == nil {
+		t.Fatal("Open replaced a regular file")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "data" {
+		t.Errorf("file at the socket path = %q, %v; want it untouched", data, err)
+	}
+}
+
+// TestSystemdHelper runs in a child process started by openInherited, which
+// passes it sockets the way systemd does; it prints the listeners of each group
+func TestSystemdHelper(t *testing.T) {
+	groupsEnv, ok := os.LookupEnv("GOOSE_TEST_LISTEN_GROUPS")
+	if !ok {
+		t.Skip("run by the systemd tests")
+	}
+	// systemd sets LISTEN_PID to the pid of the service, unknown before the child starts
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+
+	var groups [][]string
+	for _, group := range strings.Split(groupsEnv, ";") {
+		groups = append(groups, strings.Split(group, ","))
+	}
+	listeners, err := listener.Open(groups...)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	for _, group := range listeners {
+		var addresses []string
+		for _, ln := range group {
+			addresses = append(addresses, ln.Addr().String())
+		}
+		fmt.Println(strings.Join(addresses, ","))
+	}
+}
+
+// openInherited opens groups in a child process that inherits one TCP socket
+// per name in names, and returns the socket addresses by name and the output
+// of TestSystemdHelper
+func openInherited(t *testing.T, names []string, groups string) (map[string]string, []string) {
+	t.Helper()
+
+	addresses := make(map[string]string)
+	var files []*os.File
+	for i, name := range names {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		defer ln.Close()
+		file, err := ln.(*net.TCPListener).File()
+		if err != nil {
+			t.Fatalf("listener file: %v", err)
+		}
+		defer file.Close()
+		files = append(files, file)
+		addresses[name+strconv.Itoa(i)] = ln.Addr().String()
+	}
+
+	cmd := exec.CommandContext(t.Context(), os.Args[0], "-test.run=^TestSystemdHelper$")
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(),
+		"GOOSE_TEST_LISTEN_GROUPS="+groups,
+		"LISTEN_FDS="+strconv.Itoa(len(files)),
+		"LISTEN_FDNAMES="+strings.Join(names, ":"),
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("run helper: %v\n%s", err, out)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "PASS" {
+			lines = append(lines, line)
+		}
+	}
+	return addresses, lines
+}
+
+func TestOpenSystemd(t *testing.T) {
+	// sockets are keyed by name and position, e.g. web0 for the first socket named web
+	addresses, got := openInherited(t, []string{"web", "admin", "web", "metrics"}, "systemd:web;systemd:admin,systemd")
+	want := []string{
+		addresses["web0"] + "," + addresses["web2"],
+		addresses["admin1"] + "," + addresses["metrics3"],
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("listeners = %q, want %q", got, want)
+	}
+}
+
+func TestOpenSystemdErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		names  []string
+		groups string
+		want   string
+	}{
+		{name: "unknown name", names: []string{"web"}, groups: "systemd:admin", want: `systemd passed no socket named "admin"`},
+		{name: "nothing left", names: []string{"web"}, groups: "systemd:web;systemd", want: "no unclaimed sockets"},
+		{name: "no sockets", groups: "systemd", want: "no unclaimed sockets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := openInherited(t, tt.names, tt.groups)
+			if len(got) != 1 || !strings.HasPrefix(got[0], "error:") || !strings.Contains(got[0], tt.want) {
+				t.Errorf("output = %q, want an error containing %q", got, tt.want)
+			}
+		})
+	}
+}
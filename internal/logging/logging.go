@@ -0,0 +1,238 @@
+// Package logging correlates log lines with the request that produced them.
+// Middleware and Interceptor put a request-scoped *slog.Logger in the context
+// and emit one access log line per request.
+package logging
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+	"unicode"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds caller-supplied request IDs so they can't bloat log lines
+const maxRequestIDLength = 128
+
+type contextKey struct{}
+
+// request is the state shared by everything handling one request
+type request struct {
+	id string
+
+	mu sync.Mutex
+	// attrs are added to the access log line, e.g. the user ID once authenticated
+	attrs []slog.Attr
+	// rpc is set by Interceptor, which then writes the access log line instead of Middleware
+	rpc bool
+}
+
+// scope is the context value: the request and the logger for the current stage of handling it
+type scope struct {
+	req    *request
+	logger *slog.Logger
+}
+
+func fromContext(ctx context.Context) (scope, bool) {
+	s, ok := ctx.Value(contextKey{}).(scope)
+	return s, ok
+}
+
+// FromContext returns the request-scoped logger, or fallback outside a request
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if s, ok := fromContext(ctx); ok {
+		return s.logger
+	}
+	return fallback
+}
+
+// RequestID returns the ID of the request being handled
+func RequestID(ctx context.Context) (string, bool) {
+	s, ok := fromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return s.req.id, true
+}
+
+// AddAttrs returns a context whose logger includes attrs. The attributes are
+// also written to the request's access log line.
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	s, ok := fromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	s.req.mu.Lock()
+	s.req.attrs = append(s.req.attrs, attrs...)
+	s.req.mu.Unlock()
+
+	args := make([]any, len(attrs))
+	for i, attr := range attrs {
+		args[i] = attr
+	}
+	return context.WithValue(ctx, contextKey{}, scope{req: s.req, logger: s.logger.With(args...)})
+}
+
+// Middleware accepts the caller's X-Request-ID or generates one and echoes it in
+// the response. Requests that aren't RPCs, such as static assets and probes, are
+// logged at debug level; RPCs are logged by Interceptor.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := requestID(r.Header.Get(RequestIDHeader))
+			w.Header().Set(RequestIDHeader, id)
+
+			s := scope{
+				req:    &request{id: id},
+				logger: logger.With("request_id", id, "peer_addr", r.RemoteAddr),
+			}
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			ctx := context.WithValue(r.Context(), contextKey{}, s)
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			s.req.mu.Lock()
+			defer s.req.mu.Unlock()
+			if s.req.rpc {
+				return
+			}
+			s.logger.LogAttrs(ctx, slog.LevelDebug, "http request",
+				append([]slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", rec.status),
+					slog.Duration("duration", time.Since(start)),
+				}, s.req.attrs...)...,
+			)
+		})
+	}
+}
+
+// Interceptor adds the procedure to the request logger and writes one access log
+// line per RPC with its duration and Connect code. Without Middleware in front
+// it starts the request itself, accepting or generating the request ID.
+type Interceptor struct {
+	logger *slog.Logger
+}
+
+// NewInterceptor creates a logging interceptor; logger is used when Middleware isn't installed
+func NewInterceptor(logger *slog.Logger) *Interceptor {
+	return &Interceptor{logger: logger}
+}
+
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		ctx, s := i.start(ctx, req.Header(), req.Spec(), req.Peer())
+		start := time.Now()
+		res, err := next(ctx, req)
+		s.logRPC(ctx, start, err)
+		return res, err
+	}
+}
+
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, s := i.start(ctx, conn.RequestHeader(), conn.Spec(), conn.Peer())
+		start := time.Now()
+		err := next(ctx, conn)
+		s.logRPC(ctx, start, err)
+		return err
+	}
+}
+
+// start returns a context whose logger includes the procedure
+func (i *Interceptor) start(ctx context.Context, header http.Header, spec connect.Spec, peer connect.Peer) (context.Context, scope) {
+	s, ok := fromContext(ctx)
+	if !ok {
+		id := requestID(header.Get(RequestIDHeader))
+		s = scope{
+			req:    &request{id: id},
+			logger: i.logger.With("request_id", id, "peer_addr", peer.Addr),
+		}
+	}
+
+	s.req.mu.Lock()
+	s.req.rpc = true
+	s.req.mu.Unlock()
+
+	s = scope{req: s.req, logger: s.logger.With("procedure", spec.Procedure)}
+	return context.WithValue(ctx, contextKey{}, s), s
+}
+
+// logRPC writes the access log line, including attributes added further down the chain
+func (s scope) logRPC(ctx context.Context, start time.Time, err error) {
+	attrs := []slog.Attr{
+		slog.String("code", "ok"),
+		slog.Duration("duration", time.Since(start)),
+	}
+	level := slog.LevelInfo
+	if err != nil {
+		code := connect.CodeOf(err)
+		attrs[0] = slog.String("code", code.String())
+		attrs = append(attrs, slog.String("error", err.Error()))
+		if code == connect.CodeInternal || code == connect.CodeUnknown {
+			level = slog.LevelError
+		}
+	}
+
+	s.req.mu.Lock()
+	attrs = append(attrs, s.req.attrs...)
+	s.req.mu.Unlock()
+
+	s.logger.LogAttrs(ctx, level, "rpc", attrs...)
+}
+
+// requestID returns the caller's ID when it is reasonable to log, or a new one
+func requestID(id string) string {
+	if id == "" || len(id) > maxRequestIDLength {
+		return uuid.NewString()
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return uuid.NewString()
+		}
+	}
+	return id
+}
+
+// statusRecorder captures the status code written by the next handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush is required by Connect's streaming handlers
+func (r *statusRecorder) Flush() {
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack is required by the WebSocket proxy used in development
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,266 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/damejeras/goose/internal/logging"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const procedure = "/test.v1.TestService/Call"
+
+// logs collects JSON log lines
+type logs struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *logs) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *logs) logger() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(l, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// lines returns the records with the message msg
+func (l *logs) lines(t *testing.T, msg string) []map[string]any {
+	t.Helper()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(l.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decode log line %q: %v", line, err)
+		}
+		if record["msg"] == msg {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// one returns the only record with the message msg
+func (l *logs) one(t *testing.T, msg string) map[string]any {
+	t.Helper()
+
+	records := l.lines(t, msg)
+	if len(records) != 1 {
+		t.Fatalf("%d %q lines, want 1: %s", len(records), msg, l.buf.String())
+	}
+	return records[0]
+}
+
+// newRPCServer serves procedure behind the logging interceptor, and behind
+// Middleware unless bare is set. The handler logs "handling" with the request
+// logger after adding user_id, and fails with handlerErr.
+func newRPCServer(t *testing.T, logger *slog.Logger, bare bool, handlerErr error) *httptest.Server {
+	t.Helper()
+
+	call := func(ctx context.Context, req *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+		ctx = logging.AddAttrs(ctx, slog.Int64("user_id", 7))
+		logging.FromContext(ctx, nil).InfoContext(ctx, "handling")
+		if handlerErr != nil {
+			return nil, handlerErr
+		}
+		return connect.NewResponse(&emptypb.Empty{}), nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(procedure, connect.NewUnaryHandler(procedure, call, connect.WithInterceptors(logging.NewInterceptor(logger))))
+	var handler http.Handler = mux
+	if !bare {
+		handler = logging.Middleware(logger)(mux)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func callRPC(t *testing.T, server *httptest.Server, requestID string) (string, error) {
+	t.Helper()
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+procedure)
+	req := connect.NewRequest(&emptypb.Empty{})
+	if requestID != "" {
+		req.Header().Set(logging.RequestIDHeader, requestID)
+	}
+	res, err := client.CallUnary(t.Context(), req)
+	if err != nil {
+		var connectErr *connect.Error
+		if errors.As(err, &connectErr) {
+			return connectErr.Meta().Get(logging.RequestIDHeader), err
+		}
+		t.Fatalf("CallUnary: %v", err)
+	}
+	return res.Header().Get(logging.RequestIDHeader), nil
+}
+
+func TestRequestScopedFields(t *testing.T) {
+	var l logs
+	server := newRPCServer(t, l.logger(), false, nil)
+
+	id, err := callRPC(t, server, "req-123")
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if id != "req-123" {
+		t.Errorf("echoed request ID %q, want req-123", id)
+	}
+
+	// the handler's logger carries the request fields and what was added down the chain
+	handling := l.one(t, "handling")
+	for key, want := range map[string]any{"request_id": "req-123", "procedure": procedure, "user_id": float64(7)} {
+		if handling[key] != want {
+			t.Errorf("handler line %s = %v, want %v", key, handling[key], want)
+		}
+	}
+	if handling["peer_addr"] == nil {
+		t.Error("handler line has no peer_addr")
+	}
+
+	// attributes added by the handler reach the access log line
+	rpc := l.one(t, "rpc")
+	for key, want := range map[string]any{"level": "INFO", "request_id": "req-123", "procedure": procedure, "code": "ok", "user_id": float64(7)} {
+		if rpc[key] != want {
+			t.Errorf("access line %s = %v, want %v", key, rpc[key], want)
+		}
+	}
+	if rpc["duration"] == nil {
+		t.Error("access line has no duration")
+	}
+	// the RPC is logged once, by the interceptor
+	if lines := l.lines(t, "http request"); len(lines) != 0 {
+		t.Errorf("Middleware also logged the RPC: %v", lines)
+	}
+}
+
+func TestRequestScopedFieldsOnError(t *testing.T) {
+	tests := []struct {
+		err       error
+		wantCode  string
+		wantLevel string
+	}{
+		{err: connect.NewError(connect.CodeNotFound, errors.New("no such key")), wantCode: "not_found", wantLevel: "INFO"},
+		{err: connect.NewError(connect.CodeInternal, errors.New("database is locked")), wantCode: "internal", wantLevel: "ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantCode, func(t *testing.T) {
+			var l logs
+			server := newRPCServer(t, l.logger(), false, tt.err)
+
+			id, err := callRPC(t, server, "")
+			if err == nil {
+				t.Fatal("call succeeded, want an error")
+			}
+			rpc := l.one(t, "rpc")
+			if rpc["code"] != tt.wantCode || rpc["level"] != tt.wantLevel || rpc["request_id"] != id || rpc["user_id"] != float64(7) {
+				t.Errorf("access line = %v, want code %s at %s with request ID %q", rpc, tt.wantCode, tt.wantLevel, id)
+			}
+			if !strings.Contains(rpc["error"].(string), tt.err.Error()) {
+				t.Errorf("access line error = %v, want %q", rpc["error"], tt.err)
+			}
+		})
+	}
+}
+
+// TestInterceptorWithoutMiddleware checks that the interceptor starts the request
+// itself when it is the only logging layer
+func TestInterceptorWithoutMiddleware(t *testing.T) {
+	var l logs
+	server := newRPCServer(t, l.logger(), true, nil)
+
+	if _, err := callRPC(t, server, "req-456"); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	for _, msg := range []string{"handling", "rpc"} {
+		line := l.one(t, msg)
+		if line["request_id"] != "req-456" || line["procedure"] != procedure || line["peer_addr"] == nil {
+			t.Errorf("%s line = %v, want the request fields", msg, line)
+		}
+	}
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{name: "caller ID", header: "req-789", wantSame: true},
+		{name: "missing"},
+		{name: "too long", header: strings.Repeat("a", 129)},
+		{name: "control characters", header: "req\n789"},
+		{name: "non-ASCII", header: "req-☃"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l logs
+			var seen string
+			handler := logging.Middleware(l.logger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen, _ = logging.RequestID(r.Context())
+				ctx := logging.AddAttrs(r.Context(), slog.String("asset", "app.js"))
+				logging.FromContext(ctx, nil).InfoContext(ctx, "serving")
+				w.WriteHeader(http.StatusNotFound)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/assets/app.js", nil)
+			if tt.header != "" {
+				req.Header.Set(logging.RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(logging.RequestIDHeader)
+			if tt.wantSame && id != tt.header {
+				t.Errorf("request ID = %q, want %q", id, tt.header)
+			}
+			if !tt.wantSame && (id == "" || id == tt.header) {
+				t.Errorf("request ID = %q, want a generated one", id)
+			}
+			if seen != id {
+				t.Errorf("handler saw request ID %q, response has %q", seen, id)
+			}
+
+			// requests that aren't RPCs get a debug access line with the added attributes
+			access := l.one(t, "http request")
+			for key, want := range map[string]any{"level": "DEBUG", "request_id": id, "path": "/assets/app.js", "status": float64(http.StatusNotFound), "asset": "app.js"} {
+				if access[key] != want {
+					t.Errorf("access line %s = %v, want %v", key, access[key], want)
+				}
+			}
+			if serving := l.one(t, "serving"); serving["request_id"] != id || serving["asset"] != "app.js" {
+				t.Errorf("handler line = %v, want the request fields", serving)
+			}
+		})
+	}
+}
+
+func TestFromContextOutsideRequest(t *testing.T) {
+	fallback := slog.New(slog.DiscardHandler)
+	if got := logging.FromContext(t.Context(), fallback); got != fallback {
+		t.Error("FromContext outside a request didn't return the fallback")
+	}
+	if _, ok := logging.RequestID(t.Context()); ok {
+		t.Error("RequestID outside a request reported an ID")
+	}
+	if ctx := logging.AddAttrs(t.Context(), slog.Int("n", 1)); ctx != t.Context() {
+		t.Error("AddAttrs outside a request returned a new context")
+	}
+}
@@ -0,0 +1,183 @@
+// Package metrics exposes Prometheus metrics for RPCs, the database and authentication
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/recovery"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "goose"
+
+// Metrics holds the collectors goose reports to Prometheus
+type Metrics struct {
+	registry *prometheus.Registry
+
+	rpcRequests  *prometheus.CounterVec
+	rpcDuration  *prometheus.HistogramVec
+	authFailures *prometheus.CounterVec
+	apiKeyUsage  *prometheus.CounterVec
+	panics       *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them, together with Go runtime,
+// process and database pool metrics, on a dedicated registry
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		rpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_requests_total",
+			Help:      "RPCs handled, by procedure and Connect code.",
+		}, []string{"procedure", "code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_duration_seconds",
+			Help:      "Time taken to handle RPCs, by procedure.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"procedure"}),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_failures_total",
+			Help:      "RPCs rejected by authentication or authorization, by reason.",
+		}, []string{"reason"}),
+		apiKeyUsage: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "api_key_requests_total",
+			Help:      "Requests authenticated with an API key, by access (full or scoped).",
+		}, []string{"access"}),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "panics_recovered_total",
+			Help:      "Panics recovered in handlers, by kind (rpc or http).",
+		}, []string{"kind"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "goose"),
+		m.rpcRequests,
+		m.rpcDuration,
+		m.authFailures,
+		m.apiKeyUsage,
+		m.panics,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// APIKeyUsed counts a request authenticated with an API key. Keys aren't
+// labelled individually, which would add a series per key; the last use of
+// each key is recorded in the database instead.
+func (m *Metrics) APIKeyUsed(scoped bool) {
+	access := "full"
+	if scoped {
+		access = "scoped"
+	}
+	m.apiKeyUsage.WithLabelValues(access).Inc()
+}
+
+// ReportPanic counts a recovered panic; it implements recovery.Reporter
+func (m *Metrics) ReportPanic(ctx context.Context, p recovery.Panic) {
+	kind := "http"
+	if p.Procedure != "" {
+		kind = "rpc"
+	}
+	m.panics.WithLabelValues(kind).Inc()
+}
+
+// Interceptor returns a Connect interceptor recording request counts, latency,
+// result codes and auth failures. It must run outside the auth interceptor to
+// see its rejections.
+func (m *Metrics) Interceptor() connect.Interceptor {
+	return &interceptor{metrics: m}
+}
+
+type interceptor struct {
+	metrics *Metrics
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		start := time.Now()
+		res, err := next(ctx, req)
+		i.metrics.observe(req.Spec().Procedure, start, err)
+		return res, err
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler records a stream once it ends, so its latency is the stream's lifetime
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		start := time.Now()
+		err := next(ctx, conn)
+		i.metrics.observe(conn.Spec().Procedure, start, err)
+		return err
+	}
+}
+
+func (m *Metrics) observe(procedure string, start time.Time, err error) {
+	code := "ok"
+	if err != nil {
+		code = connect.CodeOf(err).String()
+	}
+	m.rpcRequests.WithLabelValues(procedure, code).Inc()
+	m.rpcDuration.WithLabelValues(procedure).Observe(time.Since(start).Seconds())
+
+	if reason, ok := authFailureReason(err); ok {
+		m.authFailures.WithLabelValues(reason).Inc()
+	}
+}
+
+// authFailureReason classifies errors returned by the auth interceptor
+func authFailureReason(err error) (string, bool) {
+	switch code := connect.CodeOf(err); {
+	case err == nil:
+		return "", false
+	case errors.Is(err, auth.ErrMissingToken):
+		return "missing_token", true
+	case errors.Is(err, auth.ErrInvalidCSRFToken):
+		return "invalid_csrf_token", true
+	case errors.Is(err, auth.ErrNoMethodPolicy):
+		return "no_method_policy", true
+	case errors.Is(err, auth.ErrInsufficientScopes):
+		return "insufficient_scopes", true
+	case errors.Is(err, auth.ErrRoleRequired):
+		return "role_required", true
+	case errors.Is(err, auth.ErrSignupNotInvited),
+		errors.Is(err, auth.ErrEmailDomainNotAllowed),
+		errors.Is(err, auth.ErrHostedDomainNotAllowed):
+		return "signup_rejected", true
+	case errors.Is(err, auth.ErrTokenExpired):
+		return "token_expired", true
+	case errors.Is(err, auth.ErrInvalidToken):
+		return "invalid_token", true
+	case code == connect.CodeUnauthenticated:
+		return "unauthenticated", true
+	case code == connect.CodePermissionDenied:
+		return "permission_denied", true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,169 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/api/gen/go/v1/v1connect"
+	"github.com/damejeras/goose/db"
+	"github.com/damejeras/goose/internal/apierror"
+	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/metrics"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+type apiKeyService struct {
+	v1connect.UnimplementedAPIKeyServiceHandler
+}
+
+func (apiKeyService) ListAPIKeys(ctx context.Context, req *connect.Request[v1.ListAPIKeysRequest]) (*connect.Response[v1.ListAPIKeysResponse], error) {
+	return connect.NewResponse(&v1.ListAPIKeysResponse{}), nil
+}
+
+func (apiKeyService) DeleteAPIKey(ctx context.Context, req *connect.Request[v1.DeleteAPIKeyRequest]) (*connect.Response[v1.DeleteAPIKeyResponse], error) {
+	return nil, apierror.NotFound("API_KEY_NOT_FOUND", errors.New("API key not found"))
+}
+
+// newServer serves apiKeyService behind the metrics and auth interceptors and
+// returns it with the metrics
+func newServer(t *testing.T) (*httptest.Server, *metrics.Metrics) {
+	t.Helper()
+
+	logger := slog.New(slog.DiscardHandler)
+	database, err := db.Open(t.Context(), logger, filepath.Join(t.TempDir(), "goose.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	policies, err := auth.LoadMethodPolicies(v1.File_v1_apikey_proto)
+	if err != nil {
+		t.Fatalf("LoadMethodPolicies: %v", err)
+	}
+	m := metrics.New(database)
+	authService := auth.NewService(auth.Config{JWTSecret: []byte(testSecret)})
+
+	mux := http.NewServeMux()
+	mux.Handle(v1connect.NewAPIKeyServiceHandler(apiKeyService{}, connect.WithInterceptors(
+		m.Interceptor(),
+		apierror.NewInterceptor(logger),
+		auth.NewInterceptor(authService, nil, nil, policies),
+	)))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, m
+}
+
+// call posts a Connect JSON request to procedure and returns the status
+func call(t *testing.T, server *httptest.Server, procedure, token string) int {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL+procedure, strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+// series returns the exposed samples of the metric name, e.g.
+// `goose_rpc_requests_total{code="ok",procedure="/api.v1.APIKeyService/ListAPIKeys"} 1`
+func series(t *testing.T, m *metrics.Metrics, name string) []string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("read metrics: %v", err)
+	}
+	var samples []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(line, name+"{") {
+			samples = append(samples, line)
+		}
+	}
+	return samples
+}
+
+// TestUnknownProcedures checks that requests for procedures goose doesn't
+// serve add no series, so clients can't grow the label set without bound
+func TestUnknownProcedures(t *testing.T) {
+	server, m := newServer(t)
+
+	authService := auth.NewService(auth.Config{JWTSecret: []byte(testSecret)})
+	jwt, err := authService.GenerateJWT(1, "user@example.com")
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	if status := call(t, server, v1connect.APIKeyServiceListAPIKeysProcedure, jwt); status != http.StatusOK {
+		t.Fatalf("ListAPIKeys: status %d", status)
+	}
+	for i := range 20 {
+		for _, procedure := range []string{
+			fmt.Sprintf("/api.v1.APIKeyService/Missing%d", i),
+			fmt.Sprintf("/api.v1.Missing%d/ListAPIKeys", i),
+		} {
+			if status := call(t, server, procedure, jwt); status != http.StatusNotFound {
+				t.Errorf("%s: status %d, want %d", procedure, status, http.StatusNotFound)
+			}
+		}
+	}
+
+	want := `goose_rpc_requests_total{code="ok",procedure="/api.v1.APIKeyService/ListAPIKeys"} 1`
+	if got := series(t, m, "goose_rpc_requests_total"); len(got) != 1 || got[0] != want {
+		t.Errorf("rpc_requests_total series = %q, want only %q", got, want)
+	}
+	for _, line := range series(t, m, "goose_rpc_duration_seconds_count") {
+		if !strings.Contains(line, `procedure="/api.v1.APIKeyService/ListAPIKeys"`) {
+			t.Errorf("rpc_duration_seconds series for an unknown procedure: %s", line)
+		}
+	}
+}
+
+func TestAuthFailures(t *testing.T) {
+	server, m := newServer(t)
+
+	authService := auth.NewService(auth.Config{JWTSecret: []byte(testSecret)})
+	jwt, err := authService.GenerateJWT(1, "user@example.com")
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	call(t, server, v1connect.APIKeyServiceListAPIKeysProcedure, "")
+	call(t, server, v1connect.APIKeyServiceListAPIKeysProcedure, "")
+	call(t, server, v1connect.APIKeyServiceListAPIKeysProcedure, "not-a-jwt")
+	// handler errors are not auth failures
+	call(t, server, v1connect.APIKeyServiceDeleteAPIKeyProcedure, jwt)
+
+	want := []string{
+		`goose_auth_failures_total{reason="invalid_token"} 1`,
+		`goose_auth_failures_total{reason="missing_token"} 2`,
+	}
+	if got := series(t, m, "goose_auth_failures_total"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("auth_failures_total series = %q, want %q", got, want)
+	}
+	notFound := `goose_rpc_requests_total{code="not_found",procedure="/api.v1.APIKeyService/DeleteAPIKey"} 1`
+	if got := series(t, m, "goose_rpc_requests_total"); !slices.Contains(got, notFound) {
+		t.Errorf("rpc_requests_total series = %q, want %q among them", got, notFound)
+	}
+}
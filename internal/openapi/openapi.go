@@ -0,0 +1,491 @@
+// Package openapi describes Connect services as an OpenAPI 3.1 document: a
+// POST operation per unary procedure for Connect clients, and an operation per
+// google.api.http binding for REST clients. Descriptions come from the proto
+// comments when the descriptors carry source info, as they do in protoc plugins.
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Security scheme names used by authenticated operations
+const (
+	SchemeJWT    = "jwt"
+	SchemeAPIKey = "apiKey"
+)
+
+const (
+	schemaPrefix      = "#/components/schemas/"
+	errorSchema       = "connect.error"
+	errorResponse     = "Error"
+	restErrorSchema   = "google.rpc.Status"
+	restErrorResponse = "RESTError"
+)
+
+// templateVariable matches a google.api.http path variable, e.g. {id} or {name=**}
+var templateVariable = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+type generator struct {
+	doc *Document
+}
+
+// Generate describes the services declared by files
+func Generate(info Info, files ...protoreflect.FileDescriptor) (*Document, error) {
+	g := &generator{doc: &Document{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			Responses: map[string]*Response{
+				errorResponse:     errorResponseOf(errorSchema),
+				restErrorResponse: errorResponseOf(restErrorSchema),
+			},
+			SecuritySchemes: map[string]*SecurityScheme{
+				SchemeJWT: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "Token returned by AuthService.Login, sent as `Authorization: Bearer <jwt>`. Browsers may use the session cookie instead.",
+				},
+				SchemeAPIKey: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "API key",
+					Description:  "Key created with APIKeyService.CreateAPIKey, sent as `Authorization: Bearer gsk_...`.",
+				},
+			},
+		},
+	}}
+	g.doc.Components.Schemas[errorSchema] = connectErrorSchema()
+	g.doc.Components.Schemas[restErrorSchema] = statusSchema()
+
+	for _, file := range files {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			service := services.Get(i)
+			g.doc.Tags = append(g.doc.Tags, Tag{Name: string(service.Name()), Description: comments(service)})
+			methods := service.Methods()
+			for j := 0; j < methods.Len(); j++ {
+				if err := g.method(methods.Get(j)); err != nil {
+					return nil, fmt.Errorf("%s: %w", methods.Get(j).FullName(), err)
+				}
+			}
+		}
+	}
+	return g.doc, nil
+}
+
+// method adds the Connect operation and the REST bindings of a procedure
+func (g *generator) method(method protoreflect.MethodDescriptor) error {
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		// streams have no JSON request/response representation in OpenAPI
+		return nil
+	}
+
+	service := method.Parent().(protoreflect.ServiceDescriptor)
+	base := Operation{
+		Summary:     string(method.Name()),
+		Description: comments(method),
+		Tags:        []string{string(service.Name())},
+		Responses: map[string]*Response{
+			"200": {
+				Description: "OK",
+				Content:     jsonContent(g.messageRef(method.Output())),
+			},
+			"default": {Ref: "#/components/responses/" + errorResponse},
+		},
+	}
+
+	policy, ok := proto.GetExtension(method.Options(), v1.E_Auth).(*v1.AuthPolicy)
+	if !ok || policy == nil || policy.GetLevel() != v1.AuthLevel_AUTH_LEVEL_PUBLIC {
+		scopes := policy.GetScopes()
+		if scopes == nil {
+			scopes = []string{}
+		}
+		base.Security = []map[string][]string{{SchemeJWT: scopes}, {SchemeAPIKey: scopes}}
+		if len(policy.GetScopes()) > 0 {
+			base.Description = strings.TrimSpace(base.Description + "\n\nRequires scopes: " + strings.Join(policy.GetScopes(), ", ") + ".")
+		}
+		if len(policy.GetRoles()) > 0 {
+			roles := make([]string, 0, len(policy.GetRoles()))
+			for _, role := range policy.GetRoles() {
+				roles = append(roles, strings.ToLower(strings.TrimPrefix(role.String(), "ROLE_")))
+			}
+			base.Description = strings.TrimSpace(base.Description + "\n\nRequires one of the roles: " + strings.Join(roles, ", ") + ".")
+		}
+	}
+	// the idempotency interceptor dedupes procedures that may have side effects
+	if options, _ := method.Options().(*descriptorpb.MethodOptions); options.GetIdempotencyLevel() == descriptorpb.MethodOptions_IDEMPOTENCY_UNKNOWN {
+		base.Parameters = append(base.Parameters, Parameter{
+			Name:        "Idempotency-Key",
+			In:          "header",
+			Description: "Retries with the same key and request replay the first response instead of repeating the call",
+			Schema:      &Schema{Type: "string", MaxLength: ptr(uint64(255))},
+		})
+	}
+
+	connectOp := base
+	connectOp.OperationID = fmt.Sprintf("%s_%s_Connect", service.Name(), method.Name())
+	connectOp.Summary = string(method.Name()) + " (Connect)"
+	connectOp.Parameters = append(slices.Clone(base.Parameters), Parameter{
+		Name:   "Connect-Protocol-Version",
+		In:     "header",
+		Schema: &Schema{Type: "string", Enum: []string{"1"}},
+	})
+	connectOp.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.messageRef(method.Input()))}
+	g.addOperation(fmt.Sprintf("/%s/%s", service.FullName(), method.Name()), "post", &connectOp)
+
+	rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if !ok || rule == nil {
+		return nil
+	}
+	bindings := append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...)
+	for i, binding := range bindings {
+		op := base
+		op.OperationID = fmt.Sprintf("%s_%s", service.Name(), method.Name())
+		op.Responses = map[string]*Response{
+			"200":     base.Responses["200"],
+			"default": {Ref: "#/components/responses/" + restErrorResponse},
+		}
+		if i > 0 {
+			op.OperationID += fmt.Sprint(i + 1)
+		}
+		httpMethod, path, err := g.binding(method.Input(), binding, &op)
+		if err != nil {
+			return err
+		}
+		g.addOperation(path, httpMethod, &op)
+	}
+	return nil
+}
+
+// binding fills in the parameters and body of a REST operation
+func (g *generator) binding(input protoreflect.MessageDescriptor, rule *annotations.HttpRule, op *Operation) (string, string, error) {
+	var httpMethod, template string
+	switch pattern := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		httpMethod, template = "get", pattern.Get
+	case *annotations.HttpRule_Put:
+		httpMethod, template = "put", pattern.Put
+	case *annotations.HttpRule_Post:
+		httpMethod, template = "post", pattern.Post
+	case *annotations.HttpRule_Delete:
+		httpMethod, template = "delete", pattern.Delete
+	case *annotations.HttpRule_Patch:
+		httpMethod, template = "patch", pattern.Patch
+	default:
+		return "", "", fmt.Errorf("unsupported google.api.http pattern %T", pattern)
+	}
+
+	bound := make(map[protoreflect.Name]bool)
+	op.Parameters = slices.Clone(op.Parameters)
+	for _, match := range templateVariable.FindAllStringSubmatch(template, -1) {
+		name := match[1]
+		field := lookupField(input, name)
+		if field == nil {
+			return "", "", fmt.Errorf("path variable %q is not a field of %s", name, input.FullName())
+		}
+		bound[protoreflect.Name(strings.SplitN(name, ".", 2)[0])] = true
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        name,
+			In:          "path",
+			Description: comments(field),
+			Required:    true,
+			Schema:      g.fieldSchema(field),
+		})
+	}
+	path := templateVariable.ReplaceAllString(template, "{$1}")
+
+	switch body := rule.GetBody(); body {
+	case "*":
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.messageRef(input))}
+		return httpMethod, path, nil
+	case "":
+	default:
+		field := input.Fields().ByName(protoreflect.Name(body))
+		if field == nil {
+			return "", "", fmt.Errorf("body field %q is not a field of %s", body, input.FullName())
+		}
+		bound[field.Name()] = true
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.fieldSchema(field))}
+	}
+
+	// The remaining scalar fields may be set with query parameters
+	fields := input.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if bound[field.Name()] || field.IsMap() || field.Message() != nil {
+			continue
+		}
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        field.JSONName(),
+			In:          "query",
+			Description: comments(field),
+			Schema:      g.fieldSchema(field),
+		})
+	}
+	return httpMethod, path, nil
+}
+
+func (g *generator) addOperation(path, method string, op *Operation) {
+	item, ok := g.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		g.doc.Paths[path] = item
+	}
+	(*item)[method] = op
+}
+
+// messageRef returns a reference to the schema of a message, adding it to the
+// components on first use
+func (g *generator) messageRef(message protoreflect.MessageDescriptor) *Schema {
+	if s := wellKnown(message); s != nil {
+		return s
+	}
+	name := string(message.FullName())
+	if _, ok := g.doc.Components.Schemas[name]; !ok {
+		s := &Schema{Type: "object", Description: comments(message), Properties: make(map[string]*Schema)}
+		// registered before the fields so recursive messages terminate
+		g.doc.Components.Schemas[name] = s
+		fields := message.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			s.Properties[field.JSONName()] = g.fieldSchema(field)
+			if required(field) {
+				s.Required = append(s.Required, field.JSONName())
+			}
+		}
+	}
+	return &Schema{Ref: schemaPrefix + name}
+}
+
+// fieldSchema follows the proto3 JSON mapping
+func (g *generator) fieldSchema(field protoreflect.FieldDescriptor) *Schema {
+	var s *Schema
+	switch {
+	case field.IsMap():
+		s = &Schema{Type: "object", AdditionalProperties: g.singularSchema(field.MapValue())}
+	case field.IsList():
+		s = &Schema{Type: "array", Items: g.singularSchema(field), UniqueItems: uniqueItems(field)}
+	default:
+		s = g.singularSchema(field)
+		stringConstraints(s, field)
+	}
+	s.Description = comments(field)
+	return s
+}
+
+func (g *generator) singularSchema(field protoreflect.FieldDescriptor) *Schema {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return &Schema{Type: "boolean"}
+	case protoreflect.StringKind:
+		return &Schema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &Schema{Type: "string", Format: "byte"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &Schema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &Schema{Type: "integer", Format: "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// 64-bit integers are JSON strings, so JavaScript doesn't lose precision
+		return &Schema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &Schema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &Schema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &Schema{Type: "number", Format: "double"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		s := &Schema{Type: "string"}
+		for i := 0; i < values.Len(); i++ {
+			s.Enum = append(s.Enum, string(values.Get(i).Name()))
+		}
+		return s
+	default:
+		return g.messageRef(field.Message())
+	}
+}
+
+// wellKnown returns the JSON representation of the well-known types that
+// aren't encoded as objects
+func wellKnown(message protoreflect.MessageDescriptor) *Schema {
+	switch message.FullName() {
+	case "google.protobuf.Timestamp":
+		return &Schema{Type: "string", Format: "date-time"}
+	case "google.protobuf.Duration":
+		return &Schema{Type: "string", Pattern: `^-?[0-9]+(\.[0-9]+)?s$`}
+	case "google.protobuf.FieldMask":
+		return &Schema{Type: "string"}
+	case "google.protobuf.Empty", "google.protobuf.Struct":
+		return &Schema{Type: "object"}
+	case "google.protobuf.Value":
+		return &Schema{}
+	case "google.protobuf.ListValue":
+		return &Schema{Type: "array", Items: &Schema{}}
+	case "google.protobuf.Any":
+		return &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"@type": {Type: "string"}},
+			Required:   []string{"@type"},
+		}
+	case "google.protobuf.StringValue":
+		return &Schema{Type: "string"}
+	case "google.protobuf.BoolValue":
+		return &Schema{Type: "boolean"}
+	case "google.protobuf.BytesValue":
+		return &Schema{Type: "string", Format: "byte"}
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return &Schema{Type: "integer"}
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return &Schema{Type: "string", Format: "int64"}
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return &Schema{Type: "number"}
+	}
+	return nil
+}
+
+// stringConstraints copies the buf.validate string rules that JSON Schema can express
+func stringConstraints(s *Schema, field protoreflect.FieldDescriptor) {
+	rules, ok := proto.GetExtension(field.Options(), validate.E_Field).(*validate.FieldRules)
+	if !ok || rules == nil || rules.GetString() == nil || s.Type != "string" {
+		return
+	}
+	rules.GetString().ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch fd.Name() {
+		case "len":
+			s.MinLength, s.MaxLength = ptr(value.Uint()), ptr(value.Uint())
+		case "min_len":
+			s.MinLength = ptr(value.Uint())
+		case "max_len":
+			s.MaxLength = ptr(value.Uint())
+		case "pattern":
+			s.Pattern = value.String()
+		case "uuid":
+			if value.Bool() {
+				s.Format = "uuid"
+			}
+		case "in":
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				s.Enum = append(s.Enum, list.Get(i).String())
+			}
+		}
+		return true
+	})
+}
+
+func uniqueItems(field protoreflect.FieldDescriptor) bool {
+	rules, ok := proto.GetExtension(field.Options(), validate.E_Field).(*validate.FieldRules)
+	return ok && rules.GetRepeated().GetUnique()
+}
+
+func required(field protoreflect.FieldDescriptor) bool {
+	rules, ok := proto.GetExtension(field.Options(), validate.E_Field).(*validate.FieldRules)
+	return ok && rules.GetRequired()
+}
+
+// lookupField resolves a dotted field path such as "parent.id"
+func lookupField(message protoreflect.MessageDescriptor, path string) protoreflect.FieldDescriptor {
+	var field protoreflect.FieldDescriptor
+	for _, name := range strings.Split(path, ".") {
+		if message == nil {
+			return nil
+		}
+		if field = message.Fields().ByName(protoreflect.Name(name)); field == nil {
+			return nil
+		}
+		message = field.Message()
+	}
+	return field
+}
+
+// comments returns the leading comment of a descriptor, or its trailing one
+func comments(desc protoreflect.Descriptor) string {
+	loc := desc.ParentFile().SourceLocations().ByDescriptor(desc)
+	text := loc.LeadingComments
+	if strings.TrimSpace(text) == "" {
+		text = loc.TrailingComments
+	}
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+// connectErrorSchema is the JSON body of a failed Connect unary call
+func connectErrorSchema() *Schema {
+	return &Schema{
+		Type:        "object",
+		Description: "Error returned by Connect operations. The google.rpc.ErrorInfo detail carries a stable reason and the request_id.",
+		Properties: map[string]*Schema{
+			"code": {Type: "string", Enum: []string{
+				"canceled", "unknown", "invalid_argument", "deadline_exceeded", "not_found",
+				"already_exists", "permission_denied", "resource_exhausted", "failed_precondition",
+				"aborted", "out_of_range", "unimplemented", "internal", "unavailable",
+				"data_loss", "unauthenticated",
+			}},
+			"message": {Type: "string"},
+			"details": {Type: "array", Items: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"type":  {Type: "string", Description: "Fully-qualified message name, e.g. google.rpc.ErrorInfo"},
+					"value": {Type: "string", Format: "byte", Description: "The detail in binary protobuf"},
+					"debug": {Type: "object", Description: "The detail in JSON"},
+				},
+			}},
+		},
+		Required: []string{"code"},
+	}
+}
+
+// statusSchema is the JSON body of a failed REST call, a google.rpc.Status
+func statusSchema() *Schema {
+	return &Schema{
+		Type:        "object",
+		Description: "Error returned by REST operations. The google.rpc.ErrorInfo detail carries a stable reason and the request_id.",
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer", Description: "gRPC status code, e.g. 16 for unauthenticated"},
+			"message": {Type: "string"},
+			"details": {Type: "array", Items: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"@type": {Type: "string", Description: "Type URL of the detail, e.g. type.googleapis.com/google.rpc.ErrorInfo"},
+				},
+				AdditionalProperties: &Schema{Description: "Fields of the detail in JSON"},
+			}},
+		},
+		Required: []string{"code"},
+	}
+}
+
+func errorResponseOf(schema string) *Response {
+	return &Response{
+		Description: "Error",
+		Headers: map[string]*Header{
+			"Retry-After": {Description: "Seconds to wait before retrying", Schema: &Schema{Type: "integer"}},
+		},
+		Content: jsonContent(&Schema{Ref: schemaPrefix + schema}),
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
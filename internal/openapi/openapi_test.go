@@ -0,0 +1,236 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/api/gen/go/v1/v1openapi"
+	"github.com/damejeras/goose/internal/openapi"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+)
+
+func generate(t *testing.T) *openapi.Document {
+	t.Helper()
+
+	doc, err := openapi.Generate(openapi.Info{Title: "goose API", Version: "api.v1"},
+		v1.File_v1_apikey_proto, v1.File_v1_auth_proto, v1.File_v1_device_proto)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	return doc
+}
+
+// operation returns the operation for method and path, failing if there is none
+func operation(t *testing.T, doc *openapi.Document, method, path string) *openapi.Operation {
+	t.Helper()
+
+	item, ok := doc.Paths[path]
+	if !ok || (*item)[method] == nil {
+		t.Fatalf("no %s %s operation; paths: %v", method, path, slices.Sorted(maps.Keys(doc.Paths)))
+	}
+	return (*item)[method]
+}
+
+func parameter(op *openapi.Operation, in, name string) *openapi.Parameter {
+	for i, p := range op.Parameters {
+		if p.In == in && p.Name == name {
+			return &op.Parameters[i]
+		}
+	}
+	return nil
+}
+
+func TestConnectOperations(t *testing.T) {
+	doc := generate(t)
+
+	for _, procedure := range []string{
+		"/api.v1.AuthService/Login",
+		"/api.v1.AuthService/GetCurrentUser",
+		"/api.v1.APIKeyService/CreateAPIKey",
+		"/api.v1.DeviceAuthService/Approve",
+	} {
+		op := operation(t, doc, "post", procedure)
+		if p := parameter(op, "header", "Connect-Protocol-Version"); p == nil || !slices.Equal(p.Schema.Enum, []string{"1"}) {
+			t.Errorf("%s: Connect-Protocol-Version parameter = %+v", procedure, p)
+		}
+		if op.RequestBody == nil || !op.RequestBody.Required {
+			t.Errorf("%s: no required request body", procedure)
+		}
+		if got := op.Responses["default"].Ref; got != "#/components/responses/Error" {
+			t.Errorf("%s: error response %q, want the Connect error", procedure, got)
+		}
+	}
+}
+
+func TestRESTOperations(t *testing.T) {
+	doc := generate(t)
+
+	list := operation(t, doc, "get", "/v1/api-keys")
+	if list.OperationID != "APIKeyService_ListAPIKeys" || list.RequestBody != nil {
+		t.Errorf("list: operation %q, body %+v", list.OperationID, list.RequestBody)
+	}
+	if got := list.Responses["default"].Ref; got != "#/components/responses/RESTError" {
+		t.Errorf("list: error response %q, want the REST error", got)
+	}
+	if got := list.Responses["200"].Content["application/json"].Schema.Ref; got != "#/components/schemas/api.v1.ListAPIKeysResponse" {
+		t.Errorf("list: response schema %q", got)
+	}
+
+	// path variables are parameters and the remaining fields come from the body
+	update := operation(t, doc, "patch", "/v1/api-keys/{id}")
+	id := parameter(update, "path", "id")
+	if id == nil || !id.Required || id.Schema.Format != "uuid" {
+		t.Errorf("update: id parameter = %+v, want a required uuid", id)
+	}
+	if update.RequestBody == nil || update.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/api.v1.UpdateAPIKeyRequest" {
+		t.Errorf("update: request body = %+v", update.RequestBody)
+	}
+
+	// a binding without a body takes its only field from the path
+	remove := operation(t, doc, "delete", "/v1/api-keys/{id}")
+	if remove.RequestBody != nil || parameter(remove, "path", "id") == nil {
+		t.Errorf("delete: parameters %+v, body %+v", remove.Parameters, remove.RequestBody)
+	}
+	operation(t, doc, "post", "/v1/device/start")
+}
+
+func TestSecurity(t *testing.T) {
+	doc := generate(t)
+
+	if op := operation(t, doc, "post", "/v1/auth/login"); op.Security != nil {
+		t.Errorf("public Login requires %v", op.Security)
+	}
+	logout := operation(t, doc, "post", "/v1/auth/logout")
+	if got := logout.Security; len(got) != 2 || got[0][openapi.SchemeJWT] == nil || len(got[0][openapi.SchemeJWT]) != 0 || got[1][openapi.SchemeAPIKey] == nil {
+		t.Errorf("Logout security = %v, want either scheme without scopes", got)
+	}
+
+	me := operation(t, doc, "get", "/v1/auth/me")
+	if got := me.Security; len(got) != 2 || !slices.Equal(got[0][openapi.SchemeJWT], []string{"user:read"}) || !slices.Equal(got[1][openapi.SchemeAPIKey], []string{"user:read"}) {
+		t.Errorf("GetCurrentUser security = %v, want user:read on both schemes", got)
+	}
+	if !strings.Contains(me.Description, "Requires scopes: user:read.") {
+		t.Errorf("GetCurrentUser description %q doesn't list the scopes", me.Description)
+	}
+	for _, scheme := range []string{openapi.SchemeJWT, openapi.SchemeAPIKey} {
+		if doc.Components.SecuritySchemes[scheme] == nil {
+			t.Errorf("security scheme %s is not declared", scheme)
+		}
+	}
+}
+
+func TestRoles(t *testing.T) {
+	options := &descriptorpb.MethodOptions{}
+	proto.SetExtension(options, v1.E_Auth, &v1.AuthPolicy{
+		Level: v1.AuthLevel_AUTH_LEVEL_AUTHENTICATED,
+		Roles: []v1.Role{v1.Role_ROLE_ADMIN},
+	})
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/admin.proto"),
+		Package:    proto.String("test.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/empty.proto"},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("AdminService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Purge"),
+				InputType:  proto.String(".google.protobuf.Empty"),
+				OutputType: proto.String(".google.protobuf.Empty"),
+				Options:    options,
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("NewFile: %v", err)
+	}
+
+	doc, err := openapi.Generate(openapi.Info{Title: "test"}, file)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if op := operation(t, doc, "post", "/test.v1.AdminService/Purge"); !strings.Contains(op.Description, "Requires one of the roles: admin.") {
+		t.Errorf("description %q doesn't list the roles", op.Description)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	doc := generate(t)
+
+	tests := []struct {
+		method, path string
+		want         bool
+	}{
+		{method: "post", path: "/v1/api-keys", want: true},
+		{method: "post", path: "/api.v1.APIKeyService/CreateAPIKey", want: true},
+		{method: "get", path: "/v1/api-keys"},
+		{method: "get", path: "/v1/auth/me"},
+	}
+	for _, tt := range tests {
+		if got := parameter(operation(t, doc, tt.method, tt.path), "header", "Idempotency-Key") != nil; got != tt.want {
+			t.Errorf("%s %s: Idempotency-Key parameter %t, want %t", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	doc := generate(t)
+
+	key := doc.Components.Schemas["api.v1.APIKey"]
+	if key == nil {
+		t.Fatal("no api.v1.APIKey schema")
+	}
+	if got := key.Properties["createdAt"]; got == nil || got.Type != "string" || got.Format != "date-time" {
+		t.Errorf("createdAt = %+v, want a date-time string", got)
+	}
+	if got := key.Properties["scopes"]; got == nil || got.Type != "array" || got.Items.Type != "string" {
+		t.Errorf("scopes = %+v, want an array of strings", got)
+	}
+
+	// buf.validate rules become JSON Schema constraints
+	create := doc.Components.Schemas["api.v1.CreateAPIKeyRequest"]
+	name := create.Properties["name"]
+	if name == nil || name.MinLength == nil || *name.MinLength != 1 || name.MaxLength == nil || *name.MaxLength != 100 || name.Pattern == "" {
+		t.Errorf("name = %+v, want the length and pattern rules", name)
+	}
+	if scopes := create.Properties["scopes"]; scopes == nil || !scopes.UniqueItems {
+		t.Errorf("scopes = %+v, want unique items", scopes)
+	}
+
+	for _, name := range []string{"connect.error", "google.rpc.Status"} {
+		if doc.Components.Schemas[name] == nil {
+			t.Errorf("no %s schema", name)
+		}
+	}
+}
+
+// TestGeneratedDocument checks that the committed document describes the same
+// operations as the generator, so it was regenerated after the last proto change
+func TestGeneratedDocument(t *testing.T) {
+	operationIDs := func(doc *openapi.Document) []string {
+		var ids []string
+		for path, item := range doc.Paths {
+			for method, op := range *item {
+				ids = append(ids, method+" "+path+" "+op.OperationID)
+			}
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	var committed openapi.Document
+	if err := json.Unmarshal(v1openapi.Document, &committed); err != nil {
+		t.Fatalf("decode the committed document: %v", err)
+	}
+	want := operationIDs(generate(t))
+	if got := operationIDs(&committed); !slices.Equal(got, want) {
+		t.Errorf("committed operations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,178 @@
+// Package recovery turns panics in handlers into CodeInternal errors or HTTP 500
+// responses instead of dropped connections, and reports them
+package recovery
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"connectrpc.com/connect"
+	"github.com/damejeras/goose/internal/logging"
+)
+
+var ErrPanic = errors.New("internal error")
+
+// Panic describes a recovered panic
+type Panic struct {
+	// Value is the value passed to panic
+	Value any
+	Stack []byte
+	// RequestID is empty when the request logging middleware isn't installed
+	RequestID string
+	// Procedure is the Connect procedure for RPCs, empty for plain HTTP requests
+	Procedure string
+	// Path is the request path for plain HTTP requests
+	Path string
+}
+
+// Reporter receives every recovered panic, e.g. to count it or send it to a crash reporter
+type Reporter interface {
+	ReportPanic(ctx context.Context, p Panic)
+}
+
+// Recoverer recovers panics in Connect handlers (Interceptor) and plain HTTP
+// handlers (Middleware), logs them with their stack and passes them to its reporters
+type Recoverer struct {
+	logger    *slog.Logger
+	reporters []Reporter
+}
+
+// New creates a Recoverer that logs through logger and notifies reporters
+func New(logger *slog.Logger, reporters ...Reporter) *Recoverer {
+	return &Recoverer{logger: logger, reporters: reporters}
+}
+
+// Middleware recovers panics in next and responds with 500, unless next already
+// started the response; then the connection is aborted, as the client can't be
+// told otherwise. http.ErrAbortHandler is re-raised, since it is the standard
+// way to abort a response.
+func (r *Recoverer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		w := &startRecorder{ResponseWriter: rw}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			p := r.report(req.Context(), v, "", req.URL.Path)
+			if w.started {
+				// a truncated response must not look complete
+				panic(http.ErrAbortHandler)
+			}
+			http.Error(w, fmt.Sprintf("Internal Server Error (request ID %s)", p.RequestID), http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, req)
+	})
+}
+
+// startRecorder records whether the next handler started the response
+type startRecorder struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (r *startRecorder) WriteHeader(status int) {
+	// informational responses are followed by the real one
+	if status >= http.StatusOK {
+		r.started = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *startRecorder) Write(b []byte) (int, error) {
+	r.started = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush is required by Connect's streaming handlers
+func (r *startRecorder) Flush() {
+	r.started = true
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack is required by the WebSocket proxy used in development
+func (r *startRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.started = true
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
+
+func (r *startRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Interceptor returns a Connect interceptor that converts handler panics to CodeInternal errors
+func (r *Recoverer) Interceptor() connect.Interceptor {
+	return &interceptor{recoverer: r}
+}
+
+type interceptor struct {
+	recoverer *Recoverer
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (res connect.AnyResponse, err error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		defer func() {
+			if v := recover(); v != nil {
+				err = i.recoverer.internalError(i.recoverer.report(ctx, v, req.Spec().Procedure, ""))
+			}
+		}()
+		return next(ctx, req)
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = i.recoverer.internalError(i.recoverer.report(ctx, v, conn.Spec().Procedure, ""))
+			}
+		}()
+		return next(ctx, conn)
+	}
+}
+
+// internalError hides the panic from the caller but gives them the request ID to quote
+func (r *Recoverer) internalError(p Panic) error {
+	if p.RequestID == "" {
+		return connect.NewError(connect.CodeInternal, ErrPanic)
+	}
+	return connect.NewError(connect.CodeInternal, fmt.Errorf("%w (request ID %s)", ErrPanic, p.RequestID))
+}
+
+func (r *Recoverer) report(ctx context.Context, v any, procedure, path string) Panic {
+	requestID, _ := logging.RequestID(ctx)
+	p := Panic{
+		Value:     v,
+		Stack:     debug.Stack(),
+		RequestID: requestID,
+		Procedure: procedure,
+		Path:      path,
+	}
+
+	args := []any{"panic", fmt.Sprint(v), "stack", string(p.Stack)}
+	if path != "" {
+		args = append(args, "path", path)
+	}
+	logging.FromContext(ctx, r.logger).ErrorContext(ctx, "panic recovered", args...)
+	for _, reporter := range r.reporters {
+		reporter.ReportPanic(ctx, p)
+	}
+	return p
+}
@@ -0,0 +1,167 @@
+package recovery_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/internal/logging"
+	"github.com/damejeras/goose/internal/recovery"
+)
+
+const panicValue = "secret panic value"
+
+// reporter collects the panics it receives
+type reporter struct {
+	mu     sync.Mutex
+	panics []recovery.Panic
+}
+
+func (r *reporter) ReportPanic(ctx context.Context, p recovery.Panic) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.panics = append(r.panics, p)
+}
+
+func (r *reporter) last(t *testing.T) recovery.Panic {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.panics) != 1 {
+		t.Fatalf("reported %d panics, want 1", len(r.panics))
+	}
+	return r.panics[0]
+}
+
+// serve runs handler behind the logging middleware and the recoverer
+func serve(t *testing.T, handler http.Handler) (*httptest.Server, *reporter) {
+	t.Helper()
+
+	logger := slog.New(slog.DiscardHandler)
+	rep := &reporter{}
+	recoverer := recovery.New(logger, rep)
+	server := httptest.NewServer(logging.Middleware(logger)(recoverer.Middleware(handler)))
+	t.Cleanup(server.Close)
+	return server, rep
+}
+
+func TestMiddleware(t *testing.T) {
+	server, rep := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(panicValue)
+	}))
+
+	res, err := server.Client().Get(server.URL + "/page")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", res.StatusCode, http.StatusInternalServerError)
+	}
+	requestID := res.Header.Get(logging.RequestIDHeader)
+	if requestID == "" || !strings.Contains(string(body), requestID) {
+		t.Errorf("body %q doesn't quote the request ID %q", body, requestID)
+	}
+	if strings.Contains(string(body), panicValue) || strings.Contains(string(body), "goroutine") {
+		t.Errorf("body exposes the panic: %q", body)
+	}
+
+	p := rep.last(t)
+	if p.Value != panicValue || p.RequestID != requestID || p.Path != "/page" || len(p.Stack) == 0 {
+		t.Errorf("reported %+v", p)
+	}
+}
+
+func TestMiddlewareAfterWrite(t *testing.T) {
+	server, rep := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		http.NewResponseController(w).Flush()
+		panic(panicValue)
+	}))
+
+	res, err := server.Client().Get(server.URL + "/page")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+
+	// the response was already on its way; it is cut off rather than amended
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want the %d already sent", res.StatusCode, http.StatusOK)
+	}
+	if err == nil {
+		t.Errorf("read a complete response %q, want it aborted", body)
+	}
+	if strings.Contains(string(body), "Internal Server Error") {
+		t.Errorf("error message appended to the response: %q", body)
+	}
+	rep.last(t)
+}
+
+func TestMiddlewareAbortHandler(t *testing.T) {
+	server, rep := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	if res, err := server.Client().Get(server.URL + "/page"); err == nil {
+		res.Body.Close()
+		t.Fatalf("got status %d, want the connection aborted", res.StatusCode)
+	}
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	if len(rep.panics) != 0 {
+		t.Errorf("http.ErrAbortHandler was reported as a panic")
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	const procedure = "/api.v1.APIKeyService/ListAPIKeys"
+	logger := slog.New(slog.DiscardHandler)
+	rep := &reporter{}
+	recoverer := recovery.New(logger, rep)
+
+	mux := http.NewServeMux()
+	mux.Handle(procedure, connect.NewUnaryHandler(procedure,
+		func(ctx context.Context, req *connect.Request[v1.ListAPIKeysRequest]) (*connect.Response[v1.ListAPIKeysResponse], error) {
+			panic(panicValue)
+		},
+		connect.WithInterceptors(recoverer.Interceptor()),
+	))
+	server := httptest.NewServer(logging.Middleware(logger)(mux))
+	t.Cleanup(server.Close)
+
+	client := connect.NewClient[v1.ListAPIKeysRequest, v1.ListAPIKeysResponse](server.Client(), server.URL+procedure)
+	_, err := client.CallUnary(t.Context(), connect.NewRequest(&v1.ListAPIKeysRequest{}))
+
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) || connectErr.Code() != connect.CodeInternal {
+		t.Fatalf("error = %v, want %s", err, connect.CodeInternal)
+	}
+	requestID := connectErr.Meta().Get(logging.RequestIDHeader)
+	if requestID == "" || !strings.Contains(connectErr.Message(), requestID) {
+		t.Errorf("message %q doesn't quote the request ID %q", connectErr.Message(), requestID)
+	}
+	if strings.Contains(connectErr.Message(), panicValue) || strings.Contains(connectErr.Message(), "goroutine") || len(connectErr.Details()) != 0 {
+		t.Errorf("error exposes the panic: %v", connectErr)
+	}
+
+	p := rep.last(t)
+	if p.Value != panicValue || p.Procedure != procedure || p.RequestID != requestID || len(p.Stack) == 0 {
+		t.Errorf("reported %+v", p)
+	}
+}
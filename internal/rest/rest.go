@@ -0,0 +1,38 @@
+// Package rest serves the google.api.http annotations of Connect services as
+// RESTful JSON routes with Vanguard. Each REST request is transcoded to a
+// Connect request and served by the service's own handler, so the interceptors
+// (auth, validation, idempotency and the rest) apply exactly as they do to
+// Connect calls.
+package rest
+
+import (
+	"fmt"
+	"net/http"
+
+	"connectrpc.com/vanguard"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxBodyBytes bounds the decompressed body of a REST request
+const maxBodyBytes = 4 << 20
+
+// New serves the REST bindings of the services declared by files, transcoded to
+// target, which serves their Connect procedures. Requests that match no
+// binding, including Connect calls, go to target unchanged, so the result
+// replaces target as the handler of the server.
+func New(target http.Handler, files ...protoreflect.FileDescriptor) (http.Handler, error) {
+	var services []*vanguard.Service
+	for _, file := range files {
+		for i := 0; i < file.Services().Len(); i++ {
+			services = append(services, vanguard.NewServiceWithSchema(file.Services().Get(i), target))
+		}
+	}
+	transcoder, err := vanguard.NewTranscoder(services,
+		vanguard.WithUnknownHandler(target),
+		vanguard.WithDefaultServiceOptions(vanguard.WithMaxMessageBufferBytes(maxBodyBytes)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create transcoder: %w", err)
+	}
+	return transcoder, nil
+}
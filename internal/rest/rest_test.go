@@ -0,0 +1,224 @@
+package rest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/api/gen/go/v1/v1connect"
+	"github.com/damejeras/goose/internal/apierror"
+	"github.com/damejeras/goose/internal/auth"
+	"github.com/damejeras/goose/internal/rest"
+)
+
+const (
+	testSecret = "0123456789abcdef0123456789abcdef"
+	keyID      = "0b5a4a0e-6f7c-4f38-9d2c-6f9f0a3c1b2d"
+)
+
+type apiKeyService struct {
+	v1connect.UnimplementedAPIKeyServiceHandler
+}
+
+func (apiKeyService) ListAPIKeys(ctx context.Context, req *connect.Request[v1.ListAPIKeysRequest]) (*connect.Response[v1.ListAPIKeysResponse], error) {
+	userID, _ := auth.GetUserIDFromContext(ctx)
+	return connect.NewResponse(&v1.ListAPIKeysResponse{
+		ApiKeys: []*v1.APIKey{{Name: fmt.Sprintf("user-%d", userID)}},
+	}), nil
+}
+
+func (apiKeyService) DeleteAPIKey(ctx context.Context, req *connect.Request[v1.DeleteAPIKeyRequest]) (*connect.Response[v1.DeleteAPIKeyResponse], error) {
+	if req.Msg.GetId() != keyID {
+		return nil, apierror.NotFound("API_KEY_NOT_FOUND", errors.New("API key not found"))
+	}
+	return connect.NewResponse(&v1.DeleteAPIKeyResponse{Success: true}), nil
+}
+
+func (apiKeyService) UpdateAPIKey(ctx context.Context, req *connect.Request[v1.UpdateAPIKeyRequest]) (*connect.Response[v1.UpdateAPIKeyResponse], error) {
+	return connect.NewResponse(&v1.UpdateAPIKeyResponse{
+		ApiKey: &v1.APIKey{Id: req.Msg.GetId(), Name: req.Msg.GetName()},
+	}), nil
+}
+
+// newTranscoderServer serves apiKeyService over Connect and REST behind the auth interceptor
+func newTranscoderServer(t *testing.T, authService *auth.Service) *httptest.Server {
+	t.Helper()
+
+	policies, err := auth.LoadMethodPolicies(v1.File_v1_apikey_proto)
+	if err != nil {
+		t.Fatalf("LoadMethodPolicies: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(v1connect.NewAPIKeyServiceHandler(apiKeyService{}, connect.WithInterceptors(
+		apierror.NewInterceptor(slog.New(slog.DiscardHandler)),
+		auth.NewInterceptor(authService, nil, nil, policies),
+	)))
+	mux.HandleFunc("/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("frontend"))
+	})
+	handler, err := rest.New(mux, v1.File_v1_apikey_proto)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// do sends a request to server and returns the status and body of the response
+func do(t *testing.T, server *httptest.Server, method, path string, body io.Reader, header http.Header) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), method, server.URL+path, body)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if header != nil {
+		req.Header = header.Clone()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	return res.StatusCode, string(data)
+}
+
+func bearer(t *testing.T, authService *auth.Service) http.Header {
+	t.Helper()
+
+	jwt, err := authService.GenerateJWT(7, "user@example.com")
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	return http.Header{"Authorization": {"Bearer " + jwt}}
+}
+
+// TestTranscoderAuth checks that REST and Connect calls of the same procedure
+// pass through the same auth interceptor
+func TestTranscoderAuth(t *testing.T) {
+	authService := auth.NewService(auth.Config{JWTSecret: []byte(testSecret)})
+	server := newTranscoderServer(t, authService)
+	token := bearer(t, authService)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		header     http.Header
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "REST without token", method: http.MethodGet, path: "/v1/api-keys", wantStatus: http.StatusUnauthorized, wantBody: `"reason":"` + auth.ReasonMissingToken + `"`},
+		{name: "Connect without token", method: http.MethodPost, path: v1connect.APIKeyServiceListAPIKeysProcedure, body: `{}`, wantStatus: http.StatusUnauthorized, wantBody: `"code":"unauthenticated"`},
+		{name: "REST with token", method: http.MethodGet, path: "/v1/api-keys", header: token, wantStatus: http.StatusOK, wantBody: `"name":"user-7"`},
+		{name: "Connect with token", method: http.MethodPost, path: v1connect.APIKeyServiceListAPIKeysProcedure, body: `{}`, header: token, wantStatus: http.StatusOK, wantBody: `"name":"user-7"`},
+		{name: "REST rename", method: http.MethodPatch, path: "/v1/api-keys/" + keyID, body: `{"name":"renamed"}`, header: token, wantStatus: http.StatusOK, wantBody: `"id":"` + keyID + `"`},
+		{name: "REST rename without token", method: http.MethodPatch, path: "/v1/api-keys/" + keyID, body: `{"name":"renamed"}`, wantStatus: http.StatusUnauthorized},
+		{name: "REST delete", method: http.MethodDelete, path: "/v1/api-keys/" + keyID, header: token, wantStatus: http.StatusOK, wantBody: `"success":true`},
+		{name: "REST delete unknown key", method: http.MethodDelete, path: "/v1/api-keys/0b5a4a0e-6f7c-4f38-9d2c-000000000000", header: token, wantStatus: http.StatusNotFound, wantBody: `"reason":"API_KEY_NOT_FOUND"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := do(t, server, tt.method, tt.path, strings.NewReader(tt.body), tt.header)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", status, tt.wantStatus, body)
+			}
+			if !json.Valid([]byte(body)) {
+				t.Fatalf("response is not JSON: %s", body)
+			}
+			if !strings.Contains(body, tt.wantBody) {
+				t.Errorf("response %s doesn't contain %s", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+// TestTranscoderCookieSession checks that REST reads authenticated by the
+// session cookie need no CSRF token, while REST writes do
+func TestTranscoderCookieSession(t *testing.T) {
+	authService := auth.NewService(auth.Config{
+		JWTSecret: []byte(testSecret),
+		Cookies:   auth.CookieConfig{Enabled: true},
+	})
+	server := newTranscoderServer(t, authService)
+
+	jwt, err := authService.GenerateJWT(7, "user@example.com")
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		csrf       string
+		wantStatus int
+	}{
+		{name: "GET without CSRF token", method: http.MethodGet, path: "/v1/api-keys", wantStatus: http.StatusOK},
+		{name: "PATCH without CSRF token", method: http.MethodPatch, path: "/v1/api-keys/" + keyID, body: `{"name":"renamed"}`, wantStatus: http.StatusForbidden},
+		{name: "PATCH with wrong CSRF token", method: http.MethodPatch, path: "/v1/api-keys/" + keyID, body: `{"name":"renamed"}`, csrf: "forged", wantStatus: http.StatusForbidden},
+		{name: "PATCH with CSRF token", method: http.MethodPatch, path: "/v1/api-keys/" + keyID, body: `{"name":"renamed"}`, csrf: authService.CSRFToken(jwt), wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Cookie": {auth.SessionCookieName + "=" + jwt}}
+			if tt.csrf != "" {
+				header.Set(auth.CSRFHeader, tt.csrf)
+			}
+			if status, body := do(t, server, tt.method, tt.path, strings.NewReader(tt.body), header); status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", status, tt.wantStatus, body)
+			}
+		})
+	}
+}
+
+func TestTranscoderContentEncoding(t *testing.T) {
+	authService := auth.NewService(auth.Config{JWTSecret: []byte(testSecret)})
+	server := newTranscoderServer(t, authService)
+	const path = "/v1/api-keys/" + keyID
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(`{"name":"compressed"}`))
+	zw.Close()
+
+	header := bearer(t, authService)
+	header.Set("Content-Encoding", "gzip")
+	status, body := do(t, server, http.MethodPatch, path, &compressed, header)
+	if status != http.StatusOK || !strings.Contains(body, `"name":"compressed"`) {
+		t.Errorf("gzip body: status %d, body %s", status, body)
+	}
+
+	header.Set("Content-Encoding", "br")
+	status, body = do(t, server, http.MethodPatch, path, strings.NewReader(`{"name":"brotli"}`), header)
+	if status != http.StatusUnsupportedMediaType {
+		t.Errorf("br body: status %d, want %d; body %s", status, http.StatusUnsupportedMediaType, body)
+	}
+}
+
+func TestTranscoderPassesThrough(t *testing.T) {
+	server := newTranscoderServer(t, auth.NewService(auth.Config{JWTSecret: []byte(testSecret)}))
+
+	if status, body := do(t, server, http.MethodGet, "/settings", nil, nil); status != http.StatusOK || body != "frontend" {
+		t.Errorf("unbound path: status %d, body %q, want the frontend", status, body)
+	}
+}
@@ -0,0 +1,173 @@
+// Package tracing sets up OpenTelemetry tracing for Connect handlers, SQL
+// queries and log records
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/otelconnect"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// Exporter selects where spans are sent
+type Exporter string
+
+const (
+	// ExporterNone disables tracing
+	ExporterNone Exporter = "none"
+	// ExporterStdout writes spans as JSON with the OpenTelemetry stdout exporter,
+	// for development. Spans go to stderr by default, so they don't interleave
+	// with the JSON logs on stdout.
+	ExporterStdout Exporter = "stdout"
+	// ExporterOTLPHTTP sends spans to an OTLP collector over HTTP
+	ExporterOTLPHTTP Exporter = "otlp-http"
+	// ExporterOTLPGRPC sends spans to an OTLP collector over gRPC
+	ExporterOTLPGRPC Exporter = "otlp-grpc"
+)
+
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+// instrumentationName names the tracer used for spans created by goose itself
+const instrumentationName = "github.com/damejeras/goose"
+
+type Config struct {
+	Exporter Exporter
+	// Endpoint is the OTLP collector host:port; empty uses the OTEL_EXPORTER_OTLP_* environment variables
+	Endpoint string
+	// Insecure disables TLS to the OTLP collector
+	Insecure bool
+	// SampleRatio is the fraction of new traces that are recorded; requests with a
+	// sampled parent are always recorded
+	SampleRatio float64
+	// TrustRemote makes server spans children of the caller's span instead of only linking to it
+	TrustRemote bool
+	// Writer receives the spans of ExporterStdout; nil means os.Stderr
+	Writer io.Writer
+}
+
+// Validate reports whether the exporter is known
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case ExporterNone, ExporterStdout, ExporterOTLPHTTP, ExporterOTLPGRPC:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownExporter, c.Exporter)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("sample ratio must be between 0 and 1, got %v", c.SampleRatio)
+	}
+	return nil
+}
+
+// Provider owns the configured tracer provider
+type Provider struct {
+	config   Config
+	provider *sdktrace.TracerProvider
+}
+
+// Setup installs a global tracer provider exporting to the configured exporter,
+// and the W3C trace context and baggage propagators. With ExporterNone the
+// global no-op provider is left in place.
+func Setup(ctx context.Context, config Config, serviceName string) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if config.Exporter == ExporterNone || config.Exporter == "" {
+		return &Provider{config: config}, nil
+	}
+
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return newProvider(config, serviceName, sdktrace.WithBatcher(exporter))
+}
+
+// newProvider installs a global tracer provider that hands spans to the processor
+func newProvider(config Config, serviceName string, processor sdktrace.TracerProviderOption) (*Provider, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		processor,
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return &Provider{config: config, provider: provider}, nil
+}
+
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case ExporterStdout:
+		w := config.Writer
+		if w == nil {
+			w = os.Stderr
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownExporter, config.Exporter)
+	}
+}
+
+// Interceptor returns a Connect interceptor that starts a span per RPC and
+// propagates W3C trace context
+func (p *Provider) Interceptor() (connect.Interceptor, error) {
+	opts := []otelconnect.Option{otelconnect.WithoutMetrics()}
+	if p.provider != nil {
+		opts = append(opts, otelconnect.WithTracerProvider(p.provider))
+	}
+	if p.config.TrustRemote {
+		opts = append(opts, otelconnect.WithTrustRemote())
+	}
+	interceptor, err := otelconnect.NewInterceptor(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create tracing interceptor: %w", err)
+	}
+	return interceptor, nil
+}
+
+// Shutdown flushes buffered spans and stops the exporter
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+	return p.provider.Shutdown(ctx)
+}
@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/damejeras/goose/db"
+	"github.com/damejeras/goose/db/sqlc"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const createUserProcedure = "/test.v1.UserService/CreateUser"
+
+func TestTraceRPC(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider, err := newProvider(Config{SampleRatio: 1}, "goose-test", sdktrace.WithSyncer(exporter))
+	if err != nil {
+		t.Fatalf("newProvider: %v", err)
+	}
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	interceptor, err := provider.Interceptor()
+	if err != nil {
+		t.Fatalf("Interceptor: %v", err)
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&logs, nil)))
+	database, err := db.Open(t.Context(), slog.New(slog.DiscardHandler), filepath.Join(t.TempDir(), "goose.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	queries := sqlc.New(WrapDB(database))
+
+	// The handler runs two queries and logs once, all within the RPC's span
+	createUser := func(ctx context.Context, req *connect.Request[emptypb.Empty]) (*connect.Response[wrapperspb.Int64Value], error) {
+		user, err := queries.CreateUser(ctx, sqlc.CreateUserParams{
+			Email:    "user@example.com",
+			GoogleID: sql.NullString{String: "google-1", Valid: true},
+			Name:     "Test User",
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := queries.GetUser(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		logger.InfoContext(ctx, "user created", "user_id", user.ID)
+		return connect.NewResponse(wrapperspb.Int64(user.ID)), nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(createUserProcedure, connect.NewUnaryHandler(createUserProcedure, createUser, connect.WithInterceptors(interceptor)))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := connect.NewClient[emptypb.Empty, wrapperspb.Int64Value](server.Client(), server.URL+createUserProcedure)
+	if _, err := client.CallUnary(t.Context(), connect.NewRequest(&emptypb.Empty{})); err != nil {
+		t.Fatalf("CallUnary: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	var rpc tracetest.SpanStub
+	var rpcSpans int
+	for _, span := range spans {
+		if span.SpanKind == trace.SpanKindServer {
+			rpc = span
+			rpcSpans++
+		}
+	}
+	if rpcSpans != 1 {
+		t.Fatalf("got %d server spans, want 1; spans: %v", rpcSpans, spanNames(spans))
+	}
+
+	queriesByName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		if span.SpanKind == trace.SpanKindClient {
+			queriesByName[span.Name] = span
+		}
+	}
+	for _, name := range []string{"sqlc.CreateUser", "sqlc.GetUser"} {
+		query, ok := queriesByName[name]
+		if !ok {
+			t.Errorf("no %s span; spans: %v", name, spanNames(spans))
+			continue
+		}
+		if query.Parent.SpanID() != rpc.SpanContext.SpanID() || query.SpanContext.TraceID() != rpc.SpanContext.TraceID() {
+			t.Errorf("%s is not a child of the RPC span %s", name, rpc.Name)
+		}
+	}
+
+	var record struct {
+		Msg     string `json:"msg"`
+		TraceID string `json:"trace_id"`
+		SpanID  string `json:"span_id"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatalf("decode log record %q: %v", logs.String(), err)
+	}
+	if record.TraceID != rpc.SpanContext.TraceID().String() || record.SpanID != rpc.SpanContext.SpanID().String() {
+		t.Errorf("log record has trace %s span %s, want trace %s span %s",
+			record.TraceID, record.SpanID, rpc.SpanContext.TraceID(), rpc.SpanContext.SpanID())
+	}
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	return names
+}
@@ -0,0 +1,79 @@
+package validation
+
+import (
+	"context"
+	"errors"
+
+	"buf.build/go/protovalidate"
+	"connectrpc.com/connect"
+	"github.com/damejeras/goose/internal/apierror"
+	"google.golang.org/protobuf/proto"
+)
+
+// Interceptor rejects requests that violate their message's rules with an
+// InvalidArgument *apierror.Error carrying google.rpc.BadRequest and
+// buf.validate.Violations details. It must run inside apierror.Interceptor.
+type Interceptor struct {
+	validator *Validator
+}
+
+// NewInterceptor creates a validation interceptor
+func NewInterceptor(validator *Validator) *Interceptor {
+	return &Interceptor{validator: validator}
+}
+
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		if err := i.validate(req.Any()); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler validates every message the handler receives
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		return next(ctx, &streamingConn{StreamingHandlerConn: conn, interceptor: i})
+	}
+}
+
+func (i *Interceptor) validate(msg any) error {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return nil
+	}
+	err := i.validator.Validate(m)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *protovalidate.ValidationError
+	if !errors.As(err, &validationErr) {
+		return apierror.Internal("failed to validate request", err)
+	}
+	apiErr := apierror.InvalidArgument(apierror.ReasonInvalidArgument, err)
+	for _, v := range validationErr.Violations {
+		apiErr.WithFieldViolation(protovalidate.FieldPathString(v.Proto.GetField()), v.Proto.GetMessage())
+	}
+	return apiErr.WithDetail(validationErr.ToProto())
+}
+
+type streamingConn struct {
+	connect.StreamingHandlerConn
+	interceptor *Interceptor
+}
+
+func (c *streamingConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	return c.interceptor.validate(msg)
+}
@@ -0,0 +1,66 @@
+// Package validation enforces the buf.validate constraints declared on request
+// messages with protovalidate
+package validation
+
+import (
+	"errors"
+	"fmt"
+
+	"buf.build/go/protovalidate"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Validator checks messages against rules compiled once at startup
+type Validator struct {
+	validator protovalidate.Validator
+}
+
+// New compiles the rules of the request messages of every method in files.
+// Lazy compilation is disabled, so a rule that doesn't compile fails startup
+// and messages of other types are rejected.
+func New(files ...protoreflect.FileDescriptor) (*Validator, error) {
+	var inputs []protoreflect.MessageDescriptor
+	for _, file := range files {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				inputs = append(inputs, methods.Get(j).Input())
+			}
+		}
+	}
+
+	validator, err := protovalidate.New(
+		protovalidate.WithMessageDescriptors(inputs...),
+		protovalidate.WithDisableLazy(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compilation errors only surface on use, so validate an empty message of
+	// every type once; violations of its rules are expected
+	var errs []error
+	for _, input := range inputs {
+		msgType, err := protoregistry.GlobalTypes.FindMessageByName(input.FullName())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("find %s: %w", input.FullName(), err))
+			continue
+		}
+		var validationErr *protovalidate.ValidationError
+		if err := validator.Validate(msgType.New().Interface()); err != nil && !errors.As(err, &validationErr) {
+			errs = append(errs, fmt.Errorf("compile rules of %s: %w", input.FullName(), err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &Validator{validator: validator}, nil
+}
+
+// Validate returns a *protovalidate.ValidationError when msg violates its rules
+func (v *Validator) Validate(msg proto.Message) error {
+	return v.validator.Validate(msg)
+}
@@ -0,0 +1,57 @@
+package validation
+
+import (
+	"slices"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"github.com/damejeras/goose/internal/apierror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestInterceptorValidate(t *testing.T) {
+	validator, err := New(v1.File_v1_auth_proto, v1.File_v1_apikey_proto, v1.File_v1_device_proto)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	interceptor := NewInterceptor(validator)
+
+	valid := &v1.CreateAPIKeyRequest{Name: "deploy", Scopes: []string{"api_keys:read"}}
+	if err := interceptor.validate(valid); err != nil {
+		t.Fatalf("validate valid request: %v", err)
+	}
+
+	err = interceptor.validate(&v1.CreateAPIKeyRequest{Scopes: []string{"api_keys:read", "api_keys:read"}})
+	apiErr, ok := apierror.From(err)
+	if !ok || apiErr.Code() != connect.CodeInvalidArgument || apiErr.Reason() != apierror.ReasonInvalidArgument {
+		t.Fatalf("error = %v, want an InvalidArgument API error", err)
+	}
+
+	var fields []string
+	var violations int
+	for _, detail := range apiErr.Connect("").Details() {
+		value, err := detail.Value()
+		if err != nil {
+			t.Fatalf("decode detail %s: %v", detail.Type(), err)
+		}
+		switch value := value.(type) {
+		case *errdetails.BadRequest:
+			for _, v := range value.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		case *validate.Violations:
+			violations = len(value.GetViolations())
+		}
+	}
+	// An empty name breaks both its length and its pattern rule
+	slices.Sort(fields)
+	fields = slices.Compact(fields)
+	if want := []string{"name", "scopes"}; !slices.Equal(fields, want) {
+		t.Errorf("field violations = %v, want %v", fields, want)
+	}
+	if violations != 3 {
+		t.Errorf("buf.validate.Violations has %d violations, want 3", violations)
+	}
+}
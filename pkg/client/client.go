@@ -0,0 +1,131 @@
+// Package client is the Go client for the goose API. It builds the v1connect
+// clients with credentials, retries, timeouts and a user agent, so services
+// talking to goose don't have to.
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/damejeras/goose/api/gen/go/v1/v1connect"
+)
+
+// Transport is the RPC protocol spoken to the server
+type Transport string
+
+const (
+	TransportConnect Transport = "connect"
+	TransportGRPC    Transport = "grpc"
+	TransportGRPCWeb Transport = "grpc-web"
+)
+
+// userAgent prefixes the User-Agent of every call
+const userAgent = "goose-go-client"
+
+type Config struct {
+	// Credentials authenticate every call; nil calls anonymously
+	Credentials Credentials
+	// Transport defaults to TransportConnect
+	Transport Transport
+	// HTTPClient defaults to a client that speaks HTTP/2 when the transport needs it
+	HTTPClient *http.Client
+	// Retry controls retries of calls that fail with a retryable code
+	Retry RetryPolicy
+	// Timeout bounds each call, including its retries, when the context has no deadline
+	Timeout time.Duration
+	// UserAgent identifies the calling application, e.g. "billing/1.4.0"
+	UserAgent string
+	// Options are passed to every v1connect client after the ones set here
+	Options []connect.ClientOption
+}
+
+// Validate checks the transport, retry policy and timeout
+func (c Config) Validate() error {
+	var errs []error
+	switch c.Transport {
+	case "", TransportConnect, TransportGRPC, TransportGRPCWeb:
+	default:
+		errs = append(errs, fmt.Errorf("unknown transport %q, use connect, grpc or grpc-web", c.Transport))
+	}
+	if c.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout must not be negative, got %s", c.Timeout))
+	}
+	if err := c.Retry.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Client holds a v1connect client for every service
+type Client struct {
+	Auth       v1connect.AuthServiceClient
+	APIKeys    v1connect.APIKeyServiceClient
+	DeviceAuth v1connect.DeviceAuthServiceClient
+
+	baseURL *url.URL
+}
+
+// New creates clients for the server at baseURL, e.g. "https://goose.example.com"
+func New(baseURL string, config Config) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("base URL %q must be http(s)://host[:port]", baseURL)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = newHTTPClient(config.Transport, u.Scheme)
+	}
+
+	ua := userAgent
+	if config.UserAgent != "" {
+		ua = config.UserAgent + " " + userAgent
+	}
+
+	// The timeout covers every attempt, credentials are added to each of them
+	// and retries see the outcome after credentials were refreshed
+	opts := []connect.ClientOption{
+		connect.WithInterceptors(
+			&timeoutInterceptor{timeout: config.Timeout},
+			&userAgentInterceptor{userAgent: ua},
+			newRetryInterceptor(config.Retry),
+			&credentialsInterceptor{credentials: config.Credentials},
+		),
+	}
+	switch config.Transport {
+	case TransportGRPC:
+		opts = append(opts, connect.WithGRPC())
+	case TransportGRPCWeb:
+		opts = append(opts, connect.WithGRPCWeb())
+	}
+	opts = append(opts, config.Options...)
+
+	return &Client{
+		Auth:       v1connect.NewAuthServiceClient(httpClient, baseURL, opts...),
+		APIKeys:    v1connect.NewAPIKeyServiceClient(httpClient, baseURL, opts...),
+		DeviceAuth: v1connect.NewDeviceAuthServiceClient(httpClient, baseURL, opts...),
+		baseURL:    u,
+	}, nil
+}
+
+// newHTTPClient negotiates HTTP/2 over TLS; gRPC needs HTTP/2 even without
+// TLS, so plain-text gRPC uses HTTP/2 with prior knowledge (h2c)
+func newHTTPClient(transport Transport, scheme string) *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	var protocols http.Protocols
+	if transport == TransportGRPC && scheme == "http" {
+		protocols.SetUnencryptedHTTP2(true)
+	} else {
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	}
+	t.Protocols = &protocols
+	return &http.Client{Transport: t}
+}
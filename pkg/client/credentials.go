@@ -0,0 +1,202 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// Credentials supply the bearer token sent with every call
+type Credentials interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// APIKey authenticates with an API key created by APIKeyService.CreateAPIKey
+type APIKey string
+
+func (k APIKey) Token(context.Context) (string, error) {
+	return string(k), nil
+}
+
+// JWT authenticates with an access token returned by AuthService.Login; it is
+// not refreshed, so calls fail with CodeUnauthenticated once it expires
+type JWT string
+
+func (t JWT) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// RefreshFunc exchanges a refresh token for a new access token. It may
+// return a new refresh token; an empty one keeps the current.
+type RefreshFunc func(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, err error)
+
+// refreshLeeway refreshes access tokens this long before they expire, so
+// that they don't expire in flight
+const refreshLeeway = 30 * time.Second
+
+var ErrNoRefreshToken = errors.New("access token expired and there is no refresh token")
+
+// TokenSource is an access token that is refreshed shortly before it
+// expires, and when the server rejects it
+type TokenSource struct {
+	refresh RefreshFunc
+	// onRefresh is called with the new tokens, e.g. to persist them
+	onRefresh func(accessToken, refreshToken string)
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiry       time.Time
+}
+
+// NewTokenSource refreshes accessToken with refresh. onRefresh may be nil.
+func NewTokenSource(accessToken, refreshToken string, refresh RefreshFunc, onRefresh func(accessToken, refreshToken string)) *TokenSource {
+	return &TokenSource{
+		refresh:      refresh,
+		onRefresh:    onRefresh,
+		accessToken:  accessToken,
+		refreshToken: refreshToken,
+		expiry:       jwtExpiry(accessToken),
+	}
+}
+
+// Token returns the access token, refreshing it first if it is about to expire
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && (s.expiry.IsZero() || time.Until(s.expiry) > refreshLeeway) {
+		return s.accessToken, nil
+	}
+	if err := s.refreshLocked(ctx); err != nil {
+		return "", err
+	}
+	return s.accessToken, nil
+}
+
+// invalidate drops token after the server rejected it, unless another call
+// already replaced it
+func (s *TokenSource) invalidate(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refreshToken == "" || s.refresh == nil {
+		return false
+	}
+	if s.accessToken == token {
+		s.accessToken = ""
+	}
+	return true
+}
+
+func (s *TokenSource) refreshLocked(ctx context.Context) error {
+	if s.refreshToken == "" || s.refresh == nil {
+		return connect.NewError(connect.CodeUnauthenticated, ErrNoRefreshToken)
+	}
+	accessToken, refreshToken, err := s.refresh(ctx, s.refreshToken)
+	if err != nil {
+		return err
+	}
+	s.accessToken = accessToken
+	if refreshToken != "" {
+		s.refreshToken = refreshToken
+	}
+	s.expiry = jwtExpiry(accessToken)
+	if s.onRefresh != nil {
+		s.onRefresh(s.accessToken, s.refreshToken)
+	}
+	return nil
+}
+
+// jwtExpiry reads the exp claim without verifying the token, which is the
+// server's job; tokens that aren't JWTs never expire as far as the client knows
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// credentialsInterceptor sets the Authorization header. A call rejected with
+// CodeUnauthenticated is repeated once if the credentials can be refreshed.
+type credentialsInterceptor struct {
+	credentials Credentials
+}
+
+func (i *credentialsInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	if i.credentials == nil {
+		return next
+	}
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		token, err := i.authorize(ctx, req.Header())
+		if err != nil {
+			return nil, err
+		}
+		res, err := next(ctx, req)
+		source, ok := i.credentials.(*TokenSource)
+		if !ok || connect.CodeOf(err) != connect.CodeUnauthenticated || !source.invalidate(token) {
+			return res, err
+		}
+		if _, err := i.authorize(ctx, req.Header()); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *credentialsInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	if i.credentials == nil {
+		return next
+	}
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		if _, err := i.authorize(ctx, conn.RequestHeader()); err != nil {
+			return &failedConn{StreamingClientConn: conn, err: err}
+		}
+		return conn
+	}
+}
+
+func (i *credentialsInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+func (i *credentialsInterceptor) authorize(ctx context.Context, header http.Header) (string, error) {
+	token, err := i.credentials.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	header.Set("Authorization", "Bearer "+token)
+	return token, nil
+}
+
+// failedConn fails a stream whose credentials couldn't be obtained
+type failedConn struct {
+	connect.StreamingClientConn
+	err error
+}
+
+func (c *failedConn) Send(any) error {
+	_ = c.StreamingClientConn.CloseRequest()
+	return c.err
+}
+
+func (c *failedConn) Receive(any) error {
+	return c.err
+}
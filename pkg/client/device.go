@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"connectrpc.com/connect"
+	v1 "github.com/damejeras/goose/api/gen/go/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// Reasons the server gives while a device login is in progress
+const (
+	reasonAuthorizationPending = "AUTHORIZATION_PENDING"
+	reasonSlowDown             = "SLOW_DOWN"
+)
+
+// DeviceLogin signs in with the device authorization grant: it starts a
+// login, passes the codes to prompt so that the user can approve the login
+// in a browser, and polls until the user approves or denies it, or the code
+// expires. The verification URIs passed to prompt are absolute.
+func (c *Client) DeviceLogin(ctx context.Context, clientName string, prompt func(*v1.StartDeviceAuthResponse)) (*v1.PollDeviceAuthResponse, error) {
+	start, err := c.DeviceAuth.Start(ctx, connect.NewRequest(&v1.StartDeviceAuthRequest{ClientName: clientName}))
+	if err != nil {
+		return nil, err
+	}
+	codes := start.Msg
+	if u, err := c.baseURL.Parse(codes.VerificationUri); err == nil {
+		codes.VerificationUri = u.String()
+	}
+	if u, err := c.baseURL.Parse(codes.VerificationUriComplete); err == nil {
+		codes.VerificationUriComplete = u.String()
+	}
+	prompt(codes)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(codes.ExpiresIn)*time.Second)
+	defer cancel()
+	interval := time.Duration(codes.Interval) * time.Second
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		res, err := c.DeviceAuth.Poll(ctx, connect.NewRequest(&v1.PollDeviceAuthRequest{DeviceCode: codes.DeviceCode}))
+		switch ErrorReason(err) {
+		case "":
+			if err != nil {
+				return nil, err
+			}
+			return res.Msg, nil
+		case reasonAuthorizationPending:
+		case reasonSlowDown:
+			if delay, ok := retryDelay(err); ok && delay > interval {
+				interval = delay
+			}
+		default:
+			return nil, err
+		}
+	}
+}
+
+// ErrorReason returns the google.rpc.ErrorInfo reason of an error returned
+// by the server, e.g. "API_KEY_NOT_FOUND", or "" if it has none
+func ErrorReason(err error) string {
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		return ""
+	}
+	for _, detail := range connectErr.Details() {
+		msg, err := detail.Value()
+		if err != nil {
+			continue
+		}
+		if info, ok := msg.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// timeoutInterceptor bounds calls whose context has no deadline
+type timeoutInterceptor struct {
+	timeout time.Duration
+}
+
+func (i *timeoutInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	if i.timeout == 0 {
+		return next
+	}
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, i.timeout)
+			defer cancel()
+		}
+		return next(ctx, req)
+	}
+}
+
+// Streams live as long as their context; the caller bounds them
+func (i *timeoutInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *timeoutInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// userAgentInterceptor replaces connect-go's User-Agent, and X-User-Agent,
+// which gRPC-Web sends because browsers don't let scripts set User-Agent
+type userAgentInterceptor struct {
+	userAgent string
+}
+
+func (i *userAgentInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		req.Header().Set("User-Agent", i.userAgent)
+		req.Header().Set("X-User-Agent", i.userAgent)
+		return next(ctx, req)
+	}
+}
+
+func (i *userAgentInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		conn.RequestHeader().Set("User-Agent", i.userAgent)
+		conn.RequestHeader().Set("X-User-Agent", i.userAgent)
+		return conn
+	}
+}
+
+func (i *userAgentInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// idempotencyKeyHeader makes calls with side effects safe to retry: the
+// server replays the first response instead of repeating the call
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy retries calls that failed with one of Codes, waiting an
+// exponentially growing, jittered backoff between attempts, or the delay the
+// server asked for. Zero fields take the defaults of DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts includes the first call; 1 disables retries
+	MaxAttempts int
+	// InitialBackoff is the upper bound of the first wait, doubling per attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts
+	MaxBackoff time.Duration
+	// Codes are the retryable codes
+	Codes []connect.Code
+}
+
+// DefaultRetryPolicy makes 3 attempts on Unavailable, ResourceExhausted and Aborted
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Codes:          []connect.Code{connect.CodeUnavailable, connect.CodeResourceExhausted, connect.CodeAborted},
+	}
+}
+
+// Validate checks that attempts and backoffs are not negative
+func (p RetryPolicy) Validate() error {
+	var errs []error
+	if p.MaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("retry max attempts must not be negative, got %d", p.MaxAttempts))
+	}
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		errs = append(errs, errors.New("retry backoffs must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = defaults.InitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = defaults.MaxBackoff
+	}
+	if p.Codes == nil {
+		p.Codes = defaults.Codes
+	}
+	return p
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	// DeviceLogin answers the polling signals of the device flow on its own
+	// schedule; retrying them here would poll faster than the server allows
+	switch ErrorReason(err) {
+	case reasonAuthorizationPending, reasonSlowDown:
+		return false
+	}
+	code := connect.CodeOf(err)
+	for _, c := range p.Codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff is the wait before the given retry, counting from 1
+func (p RetryPolicy) backoff(retry int, err error) time.Duration {
+	if delay, ok := retryDelay(err); ok {
+		return min(delay, p.MaxBackoff)
+	}
+	ceiling := p.MaxBackoff
+	if shift := retry - 1; shift < 32 && p.InitialBackoff<<shift < p.MaxBackoff {
+		ceiling = p.InitialBackoff << shift
+	}
+	// full jitter spreads retries of clients that failed together
+	return rand.N(ceiling) + 1
+}
+
+// retryDelay is the delay from a google.rpc.RetryInfo error detail
+func retryDelay(err error) (time.Duration, bool) {
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		return 0, false
+	}
+	for _, detail := range connectErr.Details() {
+		msg, err := detail.Value()
+		if err != nil {
+			continue
+		}
+		if info, ok := msg.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// retryInterceptor repeats unary calls that are safe to repeat: those
+// without side effects, idempotent ones and those with an Idempotency-Key,
+// which it adds to calls that have none
+type retryInterceptor struct {
+	policy RetryPolicy
+}
+
+func newRetryInterceptor(policy RetryPolicy) *retryInterceptor {
+	return &retryInterceptor{policy: policy.withDefaults()}
+}
+
+func (i *retryInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	if i.policy.MaxAttempts <= 1 {
+		return next
+	}
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IdempotencyLevel == connect.IdempotencyUnknown && req.Header().Get(idempotencyKeyHeader) == "" {
+			req.Header().Set(idempotencyKeyHeader, uuid.NewString())
+		}
+		for attempt := 1; ; attempt++ {
+			res, err := next(ctx, req)
+			if err == nil || attempt == i.policy.MaxAttempts || !i.policy.retryable(err) {
+				return res, err
+			}
+			timer := time.NewTimer(i.policy.backoff(attempt, err))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, err
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func (i *retryInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *retryInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
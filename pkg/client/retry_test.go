@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const pollProcedure = "/test.v1.DeviceService/Poll"
+
+func TestRetryInterceptorSkipsPollingSignals(t *testing.T) {
+	tests := []struct {
+		name         string
+		reason       string
+		wantAttempts int
+	}{
+		{name: "overloaded", reason: "OVERLOADED", wantAttempts: 3},
+		{name: "slow down", reason: reasonSlowDown, wantAttempts: 1},
+		{name: "authorization pending", reason: reasonAuthorizationPending, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			poll := func(ctx context.Context, req *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+				attempts++
+				connectErr := connect.NewError(connect.CodeResourceExhausted, nil)
+				if detail, err := connect.NewErrorDetail(&errdetails.ErrorInfo{Reason: tt.reason, Domain: "goose"}); err == nil {
+					connectErr.AddDetail(detail)
+				}
+				return nil, connectErr
+			}
+			mux := http.NewServeMux()
+			mux.Handle(pollProcedure, connect.NewUnaryHandler(pollProcedure, poll))
+			server := httptest.NewServer(mux)
+			t.Cleanup(server.Close)
+
+			retries := newRetryInterceptor(RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+			client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+pollProcedure, connect.WithInterceptors(retries))
+			_, err := client.CallUnary(t.Context(), connect.NewRequest(&emptypb.Empty{}))
+			if got := ErrorReason(err); got != tt.reason {
+				t.Fatalf("error reason = %q, want %q", got, tt.reason)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("server saw %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}